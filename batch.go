@@ -0,0 +1,259 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type batcher struct {
+	// type of values in a batch
+	typ     reflect.Type
+	size    int
+	maxWait time.Duration
+}
+
+// Batch creates a Transformer which groups values of type typ into slices of type []typ
+// a batch is emitted once it has size values, or maxWait after its first value was received (if maxWait > 0)
+// the partial batch is emitted when the input channel is closed, or when context is done
+// (in that case only if the output channel is ready to receive it)
+// batches are built by TransformAll, so use it with All or in a Chain stage with one worker,
+// Transform of a single value emits a batch with just that value
+// wrappers which transform values one by one (WithRetry, LogErrors, WithDeadLetter, WithTimeout, ...) fail with ErrStreamer
+func Batch(typ reflect.Type, size int, maxWait time.Duration) (Transformer, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", size)
+	}
+	return &batcher{typ, size, maxWait}, nil
+}
+
+// InputType is part of the Transformer interface
+func (b *batcher) InputType() reflect.Type {
+	return b.typ
+}
+
+// OutputType is part of the OutputTyper interface
+func (b *batcher) OutputType() reflect.Type {
+	return reflect.SliceOf(b.typ)
+}
+
+// Transform is part of the Transformer interface
+func (b *batcher) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	return transformOne(ctx, b, v, ch)
+}
+
+// TransformAll is part of the Streamer interface
+func (b *batcher) TransformAll(ctx context.Context, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	batch := reflect.MakeSlice(b.OutputType(), 0, b.size)
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	flush := func(send func(context.Context, chan<- interface{}, interface{}) error) error {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if batch.Len() == 0 {
+			return nil
+		}
+		out := batch.Interface()
+		batch = reflect.MakeSlice(b.OutputType(), 0, b.size)
+		return send(ctx, outCh, out)
+	}
+
+	for {
+		select {
+		case v, more := <-inCh:
+			if !more {
+				return flush(send)
+			}
+			batch = reflect.Append(batch, valueOf(v, b.typ))
+			if batch.Len() >= b.size {
+				if err := flush(send); err != nil {
+					return err
+				}
+			} else if batch.Len() == 1 && b.maxWait > 0 {
+				timer = time.NewTimer(b.maxWait)
+				timeout = timer.C
+			}
+		case <-timeout:
+			if err := flush(send); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			flush(trySend)
+			return ctx.Err()
+		}
+	}
+}
+
+type unbatcher struct {
+	// type of values in a batch
+	typ reflect.Type
+}
+
+// Unbatch creates a Transformer which emits every value of a []typ slice, it's the opposite of Batch
+func Unbatch(typ reflect.Type) Transformer {
+	return unbatcher{typ}
+}
+
+// InputType is part of the Transformer interface
+func (u unbatcher) InputType() reflect.Type {
+	return reflect.SliceOf(u.typ)
+}
+
+// OutputType is part of the OutputTyper interface
+func (u unbatcher) OutputType() reflect.Type {
+	return u.typ
+}
+
+// Transform is part of the Transformer interface
+func (u unbatcher) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	batch := reflect.ValueOf(v)
+	for i, n := 0, batch.Len(); i < n; i++ {
+		if err := send(ctx, ch, batch.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Window is a group of values with timestamps in [Start, End)
+type Window struct {
+	Start time.Time
+	End   time.Time
+	// Values is a slice of type []T, where T is the type windows are created for
+	Values interface{}
+}
+
+type windower struct {
+	// type of values in a window
+	typ   reflect.Type
+	size  time.Duration
+	slide time.Duration
+	// timestamp extractor
+	ts func(interface{}) time.Time
+}
+
+// TumblingWindow creates a Transformer which groups values of type typ into Windows of the given size
+// windows don't overlap, so every value is in exactly one window
+// see SlidingWindow for details on when windows are emitted
+func TumblingWindow(typ reflect.Type, size time.Duration, ts func(interface{}) time.Time) (Transformer, error) {
+	return SlidingWindow(typ, size, size, ts)
+}
+
+// SlidingWindow creates a Transformer which groups values of type typ into Windows of the given size
+// a new window starts every slide, so a value can be in multiple windows if slide is less than size
+// windows are based on timestamps ts returns for values, not on the time values were received.
+// a window is emitted once a value with timestamp after the window's end is received.
+// values which arrive after their window was emitted are dropped
+// all open windows are emitted when the input channel is closed, or when context is done
+// (in that case only if the output channel is ready to receive them)
+// windows are built by TransformAll, so use it with All or in a Chain stage with one worker,
+// Transform of a single value emits windows with just that value
+// wrappers which transform values one by one (WithRetry, LogErrors, WithDeadLetter, WithTimeout, ...) fail with ErrStreamer
+func SlidingWindow(typ reflect.Type, size, slide time.Duration, ts func(interface{}) time.Time) (Transformer, error) {
+	if size <= 0 || slide <= 0 {
+		return nil, fmt.Errorf("window size and slide must be positive, got %s and %s", size, slide)
+	}
+	return &windower{typ, size, slide, ts}, nil
+}
+
+// InputType is part of the Transformer interface
+func (w *windower) InputType() reflect.Type {
+	return w.typ
+}
+
+// OutputType is part of the OutputTyper interface
+func (w *windower) OutputType() reflect.Type {
+	return reflect.TypeOf(Window{})
+}
+
+// Transform is part of the Transformer interface
+func (w *windower) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	return transformOne(ctx, w, v, ch)
+}
+
+// TransformAll is part of the Streamer interface
+func (w *windower) TransformAll(ctx context.Context, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	// open windows by their start time
+	windows := map[int64]reflect.Value{}
+	// the latest timestamp seen so far
+	var watermark time.Time
+
+	// emits all windows which end before the given time in order of their start
+	flush := func(until time.Time, send func(context.Context, chan<- interface{}, interface{}) error) error {
+		var starts []int64
+		for start := range windows {
+			if end := time.Unix(0, start).Add(w.size); !end.After(until) {
+				starts = append(starts, start)
+			}
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+		for _, start := range starts {
+			window := Window{
+				Start:  time.Unix(0, start),
+				End:    time.Unix(0, start).Add(w.size),
+				Values: windows[start].Interface(),
+			}
+			delete(windows, start)
+			if err := send(ctx, outCh, window); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// far enough in the future to close all windows
+	end := time.Unix(1<<62, 0)
+
+	for {
+		select {
+		case v, more := <-inCh:
+			if !more {
+				return flush(end, send)
+			}
+			t := w.ts(v)
+			if t.After(watermark) {
+				watermark = t
+			}
+			// all windows containing t which are still open
+			for start := t.Truncate(w.slide); start.Add(w.size).After(t); start = start.Add(-w.slide) {
+				if !start.Add(w.size).After(watermark) {
+					break
+				}
+				window, ok := windows[start.UnixNano()]
+				if !ok {
+					window = reflect.MakeSlice(reflect.SliceOf(w.typ), 0, 1)
+				}
+				windows[start.UnixNano()] = reflect.Append(window, valueOf(v, w.typ))
+			}
+			if err := flush(watermark, send); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			flush(end, trySend)
+			return ctx.Err()
+		}
+	}
+}
+
+// trySend writes the value to the channel only if it's ready to receive it
+func trySend(_ context.Context, ch chan<- interface{}, v interface{}) error {
+	select {
+	case ch <- v:
+		return nil
+	default:
+		return fmt.Errorf("channel isn't ready to receive")
+	}
+}
+
+// valueOf is the same as reflect.ValueOf, but returns a zero value of typ for nil
+func valueOf(v interface{}, typ reflect.Type) reflect.Value {
+	if v == nil {
+		return reflect.Zero(typ)
+	}
+	return reflect.ValueOf(v)
+}
@@ -0,0 +1,172 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// streamOutputs streams all values through the transformer and returns all outputs
+func streamOutputs(t *testing.T, tr Transformer, vs ...interface{}) []interface{} {
+	t.Helper()
+	outCh := make(chan interface{})
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(outCh)
+		errCh <- All(context.Background(), tr, channelOf(vs...), outCh)
+	}()
+
+	var outs []interface{}
+	for out := range outCh {
+		outs = append(outs, out)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("transform shouldn't error out, got %v", err)
+	}
+	return outs
+}
+
+// channelOf returns a closed channel with the given values
+func channelOf(vs ...interface{}) <-chan interface{} {
+	ch := make(chan interface{}, len(vs))
+	for _, v := range vs {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+// batchOf creates a Batch of ints
+func batchOf(t *testing.T, size int) Transformer {
+	t.Helper()
+	b, err := Batch(reflect.TypeOf(1), size, 0)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	return b
+}
+
+func TestBatchWrapped(t *testing.T) {
+	batch := batchOf(t, 2)
+	for i, tr := range []Transformer{
+		WithRetry(batch, RetryPolicy{MaxAttempts: 1}),
+		WithTimeout(batch, time.Second),
+		LogErrors(batch),
+		WithDeadLetter(batch, &MemorySink{}),
+	} {
+		err := All(context.Background(), tr, channelOf(1, 2), make(chan interface{}, 2))
+		if !errors.Is(err, ErrStreamer) {
+			t.Fatalf("case-%d: expecting ErrStreamer, got %v", i, err)
+		}
+	}
+
+	// AllParallel streams as well
+	outCh := make(chan interface{}, 2)
+	if err := AllParallel(context.Background(), batch, 4, channelOf(1, 2, 3), outCh); err != nil {
+		t.Fatalf("transform shouldn't error out, got %v", err)
+	}
+	close(outCh)
+	var outs []interface{}
+	for out := range outCh {
+		outs = append(outs, out)
+	}
+	if want := []interface{}{[]int{1, 2}, []int{3}}; !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	if _, err := Batch(reflect.TypeOf(1), 0, 0); err == nil {
+		t.Fatalf("shouldn't be able to create a batch of size 0")
+	}
+
+	batch, err := Batch(reflect.TypeOf(1), 3, 0)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	outs := streamOutputs(t, batch, 1, 2, 3, 4, 5, 6, 7)
+	want := []interface{}{[]int{1, 2, 3}, []int{4, 5, 6}, []int{7}}
+	if !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+
+	outs = streamOutputs(t, Unbatch(reflect.TypeOf(1)), outs...)
+	want = []interface{}{1, 2, 3, 4, 5, 6, 7}
+	if !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+}
+
+func TestBatchMaxWait(t *testing.T) {
+	batch, err := Batch(reflect.TypeOf(""), 10, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+
+	inCh := make(chan interface{})
+	outCh := make(chan interface{})
+	go func() {
+		defer close(outCh)
+		All(context.Background(), batch, inCh, outCh)
+	}()
+	defer close(inCh)
+
+	inCh <- "a"
+	inCh <- "b"
+	select {
+	case out := <-outCh:
+		if want := []string{"a", "b"}; !reflect.DeepEqual(out, want) {
+			t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", out, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("partial batch wasn't emitted after max wait")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	type event struct {
+		At int // seconds
+	}
+	ts := func(v interface{}) time.Time { return time.Unix(int64(v.(event).At), 0) }
+	events := []interface{}{event{0}, event{4}, event{11}, event{3}, event{25}, event{29}}
+
+	windows := func(tr Transformer) map[int][]event {
+		out := map[int][]event{}
+		for _, o := range streamOutputs(t, tr, events...) {
+			w := o.(Window)
+			if w.End.Sub(w.Start) != 10*time.Second {
+				t.Fatalf("unexpected window size: %s", w.End.Sub(w.Start))
+			}
+			out[int(w.Start.Unix())] = w.Values.([]event)
+		}
+		return out
+	}
+
+	tumbling, err := TumblingWindow(reflect.TypeOf(event{}), 10*time.Second, ts)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	// event{3} is late, it's received after window [0, 10) was emitted
+	want := map[int][]event{0: {{0}, {4}}, 10: {{11}}, 20: {{25}, {29}}}
+	if have := windows(tumbling); !reflect.DeepEqual(have, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", have, want)
+	}
+
+	sliding, err := SlidingWindow(reflect.TypeOf(event{}), 10*time.Second, 5*time.Second, ts)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	want = map[int][]event{
+		-5: {{0}, {4}},
+		0:  {{0}, {4}},
+		5:  {{11}},
+		10: {{11}},
+		20: {{25}, {29}},
+		25: {{25}, {29}},
+	}
+	if have := windows(sliding); !reflect.DeepEqual(have, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", have, want)
+	}
+}
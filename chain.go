@@ -8,22 +8,52 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-type chain []Transformer
+type chain []Stage
 
 // Chain will create a Transformer from all given transformers
 // input type is the input type of the first transformer in chain
 // output type is the output type of the last transformer in chain
 // if we say that tx is Transform function of x-th Transformer
 // then the result is basically = tN(tN-1(...t2(t1(v))))
-func Chain(ts ...Transformer) Transformer {
-	switch len(ts) {
+// returns an error if the output type of some transformer doesn't match the input type of the next one
+func Chain(ts ...Transformer) (Transformer, error) {
+	stages := make([]Stage, len(ts))
+	for i, t := range ts {
+		stages[i] = Stage{Transformer: t}
+	}
+	return ChainWithOptions(stages...)
+}
+
+// ChainWithOptions is the same as Chain, but every stage is run according to its options
+// see Stage for more details
+func ChainWithOptions(stages ...Stage) (Transformer, error) {
+	switch len(stages) {
 	case 0:
-		panic("need at least one transformer to chain it")
+		return nil, fmt.Errorf("need at least one transformer to chain it")
 	case 1:
-		return ts[0]
-	default:
-		return chain(ts)
+		if stages[0].Workers <= 1 {
+			return stages[0].Transformer, nil
+		}
 	}
+
+	for i, s := range stages {
+		if s.Workers < 0 || s.Buffer < 0 {
+			return nil, fmt.Errorf("stage %d (%s) has negative workers (%d) or buffer (%d)", i, nameOf(s.Transformer), s.Workers, s.Buffer)
+		}
+		if _, ok := s.Transformer.(Streamer); ok && s.Workers > 1 {
+			// workers call Transform for every value, which changes what streamers like Batch do
+			return nil, fmt.Errorf("stage %d (%s) is a Streamer, it can't have %d workers", i, nameOf(s.Transformer), s.Workers)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := stages[i-1].Transformer
+		out, in := OutputType(prev), s.InputType()
+		if !assignable(out, in) {
+			return nil, fmt.Errorf("stage %d (%s) outputs %s, but stage %d (%s) expects %s", i-1, nameOf(prev), out, i, nameOf(s.Transformer), in)
+		}
+	}
+	return chain(stages), nil
 }
 
 // InputType is part of the Transformer interface
@@ -31,48 +61,66 @@ func (c chain) InputType() reflect.Type {
 	return c[0].InputType()
 }
 
+// OutputType is part of the OutputTyper interface
+func (c chain) OutputType() reflect.Type {
+	return OutputType(c[len(c)-1].Transformer)
+}
+
+// children is part of the composite interface
+func (c chain) children() []Transformer {
+	ts := make([]Transformer, len(c))
+	for i, s := range c {
+		ts[i] = s.Transformer
+	}
+	return ts
+}
+
+// stage is part of the composite interface
+func (chain) stage(i int) string {
+	return fmt.Sprintf("chain[%d]", i)
+}
+
+// withChildren is part of the composite interface, stage options are kept
+func (c chain) withChildren(ts []Transformer) Transformer {
+	stages := make(chain, len(c))
+	for i, s := range c {
+		s.Transformer = ts[i]
+		stages[i] = s
+	}
+	return stages
+}
+
 // Transform is part of the Transformer interface
+// the value is streamed through the same pipeline TransformAll builds
 func (c chain) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
-	inCh := make(chan interface{})
-	go func(ch chan<- interface{}) {
-		defer close(ch)
-		ch <- v
-	}(inCh)
+	return transformOne(ctx, c, v, ch)
+}
 
+// TransformAll is part of the Streamer interface
+// the pipeline is built once: every stage runs its workers for the whole stream,
+// and stages are connected with channels
+func (c chain) TransformAll(ctx context.Context, inCh <-chan interface{}, outCh chan<- interface{}) error {
 	group, ctx := errgroup.WithContext(ctx)
 	for i := range c {
-		tmp := make(chan interface{})
 		if i == len(c)-1 {
-			group.Go(transformOne(ctx, c[i], inCh, ch, false)) // last transformer
-		} else {
-			group.Go(transformOne(ctx, c[i], inCh, tmp, true))
+			group.Go(transformStage(ctx, c.stage(i), c[i], inCh, outCh, false)) // last transformer
+			break
 		}
+		tmp := make(chan interface{}, c[i].Buffer)
+		group.Go(transformStage(ctx, c.stage(i), c[i], inCh, tmp, true))
 		inCh = tmp
 	}
 
 	return group.Wait()
 }
 
-// runs the transformer on all values from inCh and sends them to outCh
+// runs the stage on all values from inCh and sends them to outCh
 // closes out channel when done if closeOut is true
-func transformOne(ctx context.Context, t Transformer, inCh <-chan interface{}, outCh chan<- interface{}, closeOut bool) func() error {
+func transformStage(ctx context.Context, name string, s Stage, inCh <-chan interface{}, outCh chan<- interface{}, closeOut bool) func() error {
 	return func() error {
 		if closeOut {
 			defer close(outCh)
 		}
-		for {
-			select {
-			case iface, more := <-inCh:
-				if !more {
-					return nil
-				}
-				if err := t.Transform(ctx, iface, outCh); err != nil {
-					return fmt.Errorf("transform failed: %v", err)
-				}
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-		}
-		return nil
+		return withStage(name, s.all(ctx, name, inCh, outCh))
 	}
 }
@@ -36,7 +36,10 @@ func TestChain(t *testing.T) {
 				ts[i] = chainTest(c)
 				numOutputs *= c // why it's like this, is left for the reader to find out
 			}
-			tt := Chain(ts...)
+			tt, err := Chain(ts...)
+			if err != nil {
+				t.Fatalf("can't chain transformers: %v", err)
+			}
 
 			for _, input := range []int{2, 4, 8} {
 				t.Run(fmt.Sprintf("input-%d", input), func(t *testing.T) {
@@ -64,3 +67,84 @@ func TestChain(t *testing.T) {
 		})
 	}
 }
+
+func TestChainTypes(t *testing.T) {
+	itoa, _ := FromFunction(func(x int) string { return fmt.Sprint(x) })
+	square, _ := FromFunction(func(x int) int { return x * x })
+	length, _ := FromFunction(func(s string) int { return len(s) })
+
+	for i, c := range []struct {
+		ts  []Transformer
+		err bool
+	}{
+		{ts: nil, err: true},
+		{ts: []Transformer{square, itoa, length}},
+		{ts: []Transformer{itoa, square}, err: true},
+		{ts: []Transformer{square, length}, err: true},
+		// chainTest doesn't know its output type, so it can only be checked at runtime
+		{ts: []Transformer{chainTest(1), length}},
+	} {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			tr, err := Chain(c.ts...)
+			if err != nil {
+				if !c.err {
+					t.Fatalf("should be able to chain transformers: %v", err)
+				}
+				return
+			}
+			if c.err {
+				t.Fatalf("shouldn't be able to chain transformers")
+			}
+			if want := OutputType(c.ts[len(c.ts)-1]); OutputType(tr) != want {
+				t.Fatalf("output type mismatch:\n\thave: %v\n\twant: %v", OutputType(tr), want)
+			}
+		})
+	}
+}
+
+func TestChainAll(t *testing.T) {
+	inner, err := Chain(chainTest(2), chainTest(1))
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+	tr, err := Chain(chainTest(1), inner, chainTest(3))
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+	if _, ok := tr.(Streamer); !ok {
+		t.Fatalf("chain should be a streamer")
+	}
+
+	const numInputs = 1000
+	inCh := make(chan interface{})
+	go func() {
+		defer close(inCh)
+		for i := 0; i < numInputs; i++ {
+			inCh <- i
+		}
+	}()
+
+	outCh := make(chan interface{})
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(outCh)
+		errCh <- All(context.Background(), tr, inCh, outCh)
+	}()
+
+	sum, outputs := 0, 0
+	for out := range outCh {
+		sum += out.(int)
+		outputs++
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("transform shouldn't error out, got %v", err)
+	}
+
+	// every input x produces 2*3 outputs, each one being x+4
+	if want := 6 * numInputs; outputs != want {
+		t.Fatalf("unexpected number of outputs\n\thave: %d\n\twant: %d", outputs, want)
+	}
+	if want := 6 * (numInputs*(numInputs-1)/2 + 4*numInputs); sum != want {
+		t.Fatalf("unexpected sum of outputs\n\thave: %d\n\twant: %d", sum, want)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/n1chre/transform"
+)
+
+// filesSource reads values from all files in order, or from stdin if there are no files
+type filesSource struct {
+	files     []string
+	stdin     io.Reader
+	newSource func(io.Reader) transform.Source
+}
+
+// Read is part of the transform.Source interface
+func (s filesSource) Read(ctx context.Context, ch chan<- interface{}) error {
+	if len(s.files) == 0 {
+		return s.newSource(s.stdin).Read(ctx, ch)
+	}
+	for _, name := range s.files {
+		if err := s.readFile(ctx, name, ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s filesSource) readFile(ctx context.Context, name string, ch chan<- interface{}) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := s.newSource(f).Read(ctx, ch); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Command transform runs pipeline definitions (see package pipeline) over files
+//
+// usage:
+//
+//	transform run [-format jsonl|csv|tsv|hive] [-output jsonl|csv|tsv|hive] [-skip-errors] definition [files...]
+//	transform validate definition
+//	transform graph [-format dot|mermaid] definition
+//
+// run transforms values read from the files, or from stdin if there are no files, and writes outputs to stdout
+// validate checks the definition without running it, and graph prints a diagram of the compiled pipeline
+// types used in the definition have to be declared in its types section
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/n1chre/transform"
+	"github.com/n1chre/transform/pipeline"
+)
+
+const usage = `usage:
+	transform run [-format jsonl|csv|tsv|hive] [-output jsonl|csv|tsv|hive] [-skip-errors] definition [files...]
+	transform validate definition
+	transform graph [-format dot|mermaid] definition
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with the given arguments, and returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var cmd func(context.Context, *flag.FlagSet, []string, io.Reader, io.Writer) error
+	switch args[0] {
+	case "run":
+		cmd = runCmd
+	case "validate":
+		cmd = validateCmd
+	case "graph":
+		cmd = graphCmd
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	if err := cmd(ctx, fs, args[1:], stdin, stdout); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(stderr, "transform %s: %v\n", args[0], err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// compile parses the flags, and compiles the definition which is the first argument
+// returns the rest of the arguments
+func compile(fs *flag.FlagSet, args []string) (transform.Transformer, []string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil, nil, fmt.Errorf("definition is missing")
+	}
+	t, err := pipeline.CompileFile(fs.Arg(0), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, fs.Args()[1:], nil
+}
+
+func validateCmd(_ context.Context, fs *flag.FlagSet, args []string, _ io.Reader, stdout io.Writer) error {
+	t, rest, err := compile(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+
+	out := "unknown"
+	if typ := transform.OutputType(t); typ != nil {
+		out = typ.String()
+	}
+	fmt.Fprintf(stdout, "ok: %s -> %s\n", t.InputType(), out)
+	return nil
+}
+
+func graphCmd(_ context.Context, fs *flag.FlagSet, args []string, _ io.Reader, stdout io.Writer) error {
+	format := fs.String("format", "dot", "diagram format, dot or mermaid")
+	t, rest, err := compile(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+
+	switch *format {
+	case "dot":
+		fmt.Fprint(stdout, transform.Describe(t).DOT())
+	case "mermaid":
+		fmt.Fprint(stdout, transform.Describe(t).Mermaid())
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	return nil
+}
+
+// separators are the separators of CSV formats
+var separators = map[string]rune{"csv": ',', "tsv": '\t'}
+
+func runCmd(ctx context.Context, fs *flag.FlagSet, args []string, stdin io.Reader, stdout io.Writer) error {
+	format := fs.String("format", "jsonl", "input format, jsonl, csv, tsv or hive (text format of Hive's default SerDe)")
+	output := fs.String("output", "jsonl", "output format, jsonl, csv, tsv or hive")
+	skipErrors := fs.Bool("skip-errors", false, "log values which fail to transform to stderr as JSON, and skip them")
+	t, files, err := compile(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// only the decoder and stages of the pipeline skip errors, one by one, so streaming stages (e.g. batches) keep streaming
+	skip := func(t transform.Transformer) transform.Transformer { return t }
+	if *skipErrors {
+		logger := slog.New(slog.NewJSONHandler(fs.Output(), nil))
+		skip = func(t transform.Transformer) transform.Transformer {
+			return transform.LogErrorsTo(t, logger, transform.LogOptions{})
+		}
+		if t, err = transform.Apply(t, transform.SkipStreamers(skip)); err != nil {
+			return err
+		}
+	}
+
+	var newSource func(io.Reader) transform.Source
+	switch *format {
+	case "jsonl":
+		// lines are decoded by the pipeline, so lines which can't be decoded can be skipped as well
+		if t, err = transform.Chain(skip(transform.Named("decode", transform.NewJSONDecoder(t.InputType()))), t); err != nil {
+			return err
+		}
+		newSource = transform.LineSource
+	case "csv", "tsv":
+		// rows are decoded by the pipeline, so rows which can't be decoded can be skipped as well
+		decoder, err := transform.NewCSVDecoder(t.InputType())
+		if err != nil {
+			return err
+		}
+		if t, err = transform.Chain(skip(transform.Named("decode", decoder)), t); err != nil {
+			return err
+		}
+		comma := separators[*format]
+		newSource = func(r io.Reader) transform.Source {
+			return transform.CSVSource(r, comma)
+		}
+	case "hive":
+		decoder, err := transform.NewHiveDecoder(t.InputType(), transform.HiveOptions{})
+		if err != nil {
+			return err
+		}
+		if t, err = transform.Chain(skip(transform.Named("decode", decoder)), t); err != nil {
+			return err
+		}
+		newSource = transform.LineSource
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var sink transform.Sink
+	switch *output {
+	case "jsonl":
+		sink = transform.JSONLinesSink(stdout)
+	case "csv", "tsv":
+		sink = transform.CSVSink(stdout, separators[*output])
+	case "hive":
+		typ := transform.OutputType(t)
+		if typ == nil {
+			return fmt.Errorf("output type of the pipeline is unknown, it can't be written as hive")
+		}
+		encoder, err := transform.NewHiveEncoder(typ, transform.HiveOptions{})
+		if err != nil {
+			return err
+		}
+		if t, err = transform.Chain(t, encoder); err != nil {
+			return err
+		}
+		sink = transform.LineSink(stdout)
+	default:
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+
+	src := filesSource{files: files, stdin: stdin, newSource: newSource}
+	return transform.Run(ctx, src, t, sink)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDefinition = `
+types:
+  event: {user_id: int, country: string, score: float64}
+input: event
+pipeline:
+  collapse: [country, user_id]
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	def := filepath.Join(dir, "pipeline.yaml")
+	if err := os.WriteFile(def, []byte(testDefinition), 0o644); err != nil {
+		t.Fatalf("can't write definition: %v", err)
+	}
+	csvFile := filepath.Join(dir, "events.csv")
+	if err := os.WriteFile(csvFile, []byte("user_id,country,ignored\n1,hr,x\n2,de,y\n"), 0o644); err != nil {
+		t.Fatalf("can't write input: %v", err)
+	}
+
+	cases := []struct {
+		args   []string
+		stdin  string
+		stdout string
+		stderr string
+		code   int
+	}{
+		{
+			args:   []string{"run", def},
+			stdin:  `{"user_id": 1, "country": "hr", "score": 0.5}` + "\n\n" + `{"country": "de"}`,
+			stdout: "{\"country\":\"hr\",\"user_id\":1}\n{\"country\":\"de\",\"user_id\":0}\n",
+		},
+		{
+			args:   []string{"run", "-skip-errors", def},
+			stdin:  `{"user_id": "one"}` + "\n" + `{"user_id": 2, "country": "de"}`,
+			stdout: "{\"country\":\"de\",\"user_id\":2}\n",
+			stderr: `"stage":"decode"`,
+		},
+		{
+			args:   []string{"run", "-format", "csv", def, csvFile},
+			stdout: "{\"country\":\"hr\",\"user_id\":1}\n{\"country\":\"de\",\"user_id\":2}\n",
+		},
+		{
+			args:   []string{"run", "-format", "tsv", def},
+			stdin:  "country\tuser_id\nhr\t3\n",
+			stdout: "{\"country\":\"hr\",\"user_id\":3}\n",
+		},
+		{
+			args:   []string{"run", "-format", "tsv", "-output", "csv", "-skip-errors", def},
+			stdin:  "country\tuser_id\nhr\tthree\nde\t4\n",
+			stdout: "country,user_id\nde,4\n",
+			stderr: `"stage":"decode"`,
+		},
+		{
+			args:   []string{"run", "-format", "hive", "-output", "hive", def},
+			stdin:  "1\x01hr\x010.5\n2\x01de\n",
+			stdout: "hr\x011\nde\x012\n",
+		},
+		{
+			args:  []string{"run", "-format", "csv", def},
+			stdin: "user_id\none\n",
+			code:  1,
+		},
+		{
+			args:  []string{"run", def},
+			stdin: `{"user_id": "one"}`,
+			code:  1,
+		},
+		{
+			args: []string{"run", "-format", "xml", def},
+			code: 1,
+		},
+		{
+			args:   []string{"validate", def},
+			stdout: "ok: struct { UserId int \"hive:\\\"user_id\\\"\"; Country string \"hive:\\\"country\\\"\"; Score float64 \"hive:\\\"score\\\"\" } -> struct { Country string \"hive:\\\"country\\\"\"; UserId int \"hive:\\\"user_id\\\"\" }\n",
+		},
+		{
+			args: []string{"validate", filepath.Join(dir, "missing.yaml")},
+			code: 1,
+		},
+		{
+			args: []string{"graph"},
+			code: 1,
+		},
+		{
+			args: []string{"unknown"},
+			code: 2,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), c.args, strings.NewReader(c.stdin), &stdout, &stderr)
+			if code != c.code {
+				t.Fatalf("exit code mismatch: have %d, want %d\n%s", code, c.code, stderr.String())
+			}
+			if c.code == 0 && stdout.String() != c.stdout {
+				t.Fatalf("stdout mismatch\n\thave:\t%q\n\twant:\t%q", stdout.String(), c.stdout)
+			}
+			if !strings.Contains(stderr.String(), c.stderr) {
+				t.Fatalf("stderr doesn't contain %s:\n%s", c.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestGraph(t *testing.T) {
+	def := filepath.Join(t.TempDir(), "pipeline.yaml")
+	if err := os.WriteFile(def, []byte(testDefinition), 0o644); err != nil {
+		t.Fatalf("can't write definition: %v", err)
+	}
+
+	for _, format := range []string{"dot", "mermaid"} {
+		var stdout, stderr bytes.Buffer
+		if code := run(context.Background(), []string{"graph", "-format", format, def}, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "struct collapser") {
+			t.Fatalf("%s graph doesn't contain the collapser:\n%s", format, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package transform
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// CSVRow is a record read by CSVSource
+type CSVRow struct {
+	// Line is the line the record starts at
+	Line int
+	// Header is the first record, it's shared by all rows read by one source
+	Header []string
+	// Fields are the values of the record, in the same order as the columns in the header
+	Fields []string
+}
+
+// CSVSource creates a source which reads records of CSV, or TSV if comma is '\t', and sends them as CSVRows
+// the first record is the header, use NewCSVDecoder to decode rows into structs
+func CSVSource(r io.Reader, comma rune) Source {
+	return csvSource{r, comma}
+}
+
+type csvSource struct {
+	r     io.Reader
+	comma rune
+}
+
+// Read is part of the Source interface
+func (s csvSource) Read(ctx context.Context, ch chan<- interface{}) error {
+	cr := csv.NewReader(s.r)
+	cr.Comma = s.comma
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		if err := send(ctx, ch, CSVRow{Line: line, Header: header, Fields: fields}); err != nil {
+			return err
+		}
+	}
+}
+
+// csvDecoder decodes CSVRows into structs
+type csvDecoder struct {
+	outputType reflect.Type
+	// fieldIdx maps GetStructFieldName of fields to their indexes
+	fieldIdx map[string]int
+}
+
+// NewCSVDecoder creates a transformer which decodes CSVRows into values of the given struct type
+// columns are mapped to fields by their names in the header, which are matched with GetStructFieldName of fields
+// columns without a matching field are ignored, and fields without a column are left empty
+// supported fields are strings, bools, numbers, times and pointers to those, which are nil if the column is empty
+// rows which can't be decoded fail with an error, so they can be handled with WithErrorHandler or WithDeadLetter
+func NewCSVDecoder(outputType reflect.Type) (Transformer, error) {
+	if outputType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't decode CSV into %s, it isn't a struct", outputType)
+	}
+	d := &csvDecoder{outputType: outputType, fieldIdx: map[string]int{}}
+	for i := 0; i < outputType.NumField(); i++ {
+		sf := outputType.Field(i)
+		if sf.PkgPath != "" {
+			continue // not exported
+		}
+		if !isText(sf.Type) {
+			return nil, fmt.Errorf("field %s of type %s can't be decoded from CSV", sf.Name, sf.Type)
+		}
+		d.fieldIdx[GetStructFieldName(sf)] = i
+	}
+	return d, nil
+}
+
+// InputType is part of the Transformer interface
+func (d *csvDecoder) InputType() reflect.Type {
+	return reflect.TypeOf(CSVRow{})
+}
+
+// OutputType is part of the OutputTyper interface
+func (d *csvDecoder) OutputType() reflect.Type {
+	return d.outputType
+}
+
+// Transform is part of the Transformer interface
+func (d *csvDecoder) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	row := v.(CSVRow)
+	out := reflect.New(d.outputType).Elem()
+	for i, s := range row.Fields {
+		if i >= len(row.Header) {
+			return fmt.Errorf("line %d has %d fields, but there are only %d columns", row.Line, len(row.Fields), len(row.Header))
+		}
+		idx, ok := d.fieldIdx[strings.ToLower(row.Header[i])]
+		if !ok {
+			continue
+		}
+		if err := parseText(s, out.Field(idx)); err != nil {
+			return fmt.Errorf("line %d, column %q: %v", row.Line, row.Header[i], err)
+		}
+	}
+	return send(ctx, ch, out.Interface())
+}
+
+// CSVSink creates a sink which writes structs as records of CSV, or TSV if comma is '\t'
+// the header has GetStructFieldName of fields in the order of fields,
+// which is the order of names given to NewStructCollapser, so writing its outputs projects the given columns
+// it's written before the first value, and all values have to be of the same type
+// fields are formatted so that NewCSVDecoder decodes them back into the same values
+func CSVSink(w io.Writer, comma rune) Sink {
+	return csvSink{w, comma}
+}
+
+type csvSink struct {
+	w     io.Writer
+	comma rune
+}
+
+// Write is part of the Sink interface
+func (s csvSink) Write(ctx context.Context, ch <-chan interface{}) error {
+	cw := csv.NewWriter(s.w)
+	cw.Comma = s.comma
+
+	var typ reflect.Type
+	var fields []int // indexes of exported fields
+	var record []string
+	for {
+		v, more, err := recv(ctx, ch)
+		if err != nil {
+			return err
+		}
+		if !more {
+			cw.Flush()
+			return cw.Error()
+		}
+
+		rv := reflect.ValueOf(v)
+		if typ == nil {
+			if rv.Kind() != reflect.Struct {
+				return fmt.Errorf("can't write %T as CSV, it isn't a struct", v)
+			}
+			typ = rv.Type()
+			var header []string
+			for i := 0; i < typ.NumField(); i++ {
+				if sf := typ.Field(i); sf.PkgPath == "" {
+					fields = append(fields, i)
+					header = append(header, GetStructFieldName(sf))
+				}
+			}
+			if err := cw.Write(header); err != nil {
+				return err
+			}
+			record = make([]string, len(fields))
+		} else if rv.Type() != typ {
+			return fmt.Errorf("can't write %T as CSV, previous values are %s", v, typ)
+		}
+
+		for i, idx := range fields {
+			s, err := formatText(rv.Field(idx))
+			if err != nil {
+				return fmt.Errorf("field %s: %v", typ.Field(idx).Name, err)
+			}
+			record[i] = s
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSV(t *testing.T) {
+	type row struct {
+		ID      int64 `hive:"user_id"`
+		Name    string
+		Score   *float64
+		Active  bool
+		Created time.Time
+		Visits  *uint16
+	}
+
+	score := 1.5
+	visits := uint16(3)
+	cases := []struct {
+		comma  rune
+		input  string
+		rows   []row
+		output string // written rows, the same as the input if empty
+		err    bool
+	}{
+		{
+			comma: ',',
+			input: "user_id,name,score,active,created,visits\n" +
+				"1,\"a, b\",1.5,true,2020-01-02T03:04:05Z,3\n" +
+				"2,c,,false,2020-01-02T00:00:00Z,\n",
+			rows: []row{
+				{1, "a, b", &score, true, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), &visits},
+				{2, "c", nil, false, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), nil},
+			},
+		},
+		{
+			comma: '\t',
+			input: "NAME\tignored\tUser_ID\tcreated\n" +
+				"x\ty\t7\t2020-01-02\n",
+			rows:   []row{{ID: 7, Name: "x", Created: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}},
+			output: "user_id\tname\tscore\tactive\tcreated\tvisits\n7\tx\t\tfalse\t2020-01-02T00:00:00Z\t\n",
+		},
+		{
+			comma: ',',
+			input: "user_id,name\n1,a\none,b\n",
+			err:   true,
+		},
+	}
+
+	decoder, err := NewCSVDecoder(reflect.TypeOf(row{}))
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			var buf bytes.Buffer
+			var rows []row
+			sink := sinkFunc(func(ctx context.Context, ch <-chan interface{}) error {
+				var vs []interface{}
+				for v := range ch {
+					rows = append(rows, v.(row))
+					vs = append(vs, v)
+				}
+				return CSVSink(&buf, c.comma).Write(ctx, channelOf(vs...))
+			})
+
+			err := Run(context.Background(), CSVSource(strings.NewReader(c.input), c.comma), decoder, sink)
+			if err != nil {
+				if !c.err {
+					t.Fatalf("can't run: %v", err)
+				}
+				var te *TransformError
+				if !errors.As(err, &te) || !strings.Contains(err.Error(), `line 3, column "user_id"`) {
+					t.Fatalf("expecting a TransformError of line 3, got %v", err)
+				}
+				return
+			}
+			if c.err {
+				t.Fatalf("should fail to decode")
+			}
+
+			if !reflect.DeepEqual(rows, c.rows) {
+				t.Fatalf("rows mismatch\n\thave:\t%+v\n\twant:\t%+v", rows, c.rows)
+			}
+			output := c.output
+			if output == "" {
+				output = c.input
+			}
+			if buf.String() != output {
+				t.Fatalf("output mismatch\n\thave:\t%q\n\twant:\t%q", buf.String(), output)
+			}
+		})
+	}
+}
+
+func TestCSVProjection(t *testing.T) {
+	type foo struct {
+		A int
+		B string `hive:"bee"`
+		C bool
+	}
+
+	collapser, err := NewStructCollapser(reflect.TypeOf(foo{}), []string{"c", "a"})
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := CSVSink(&buf, ',').Write(context.Background(), channelOf(streamOutputs(t, collapser, foo{1, "x", true})...)); err != nil {
+		t.Fatalf("can't write: %v", err)
+	}
+	if buf.String() != "c,a\ntrue,1\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+
+	if _, err := NewCSVDecoder(reflect.TypeOf(struct{ M map[string]int }{})); err == nil {
+		t.Fatalf("shouldn't be able to decode maps")
+	}
+	if err := CSVSink(&buf, ',').Write(context.Background(), channelOf(1)); err == nil {
+		t.Fatalf("shouldn't be able to write ints")
+	}
+	if err := CSVSink(&buf, ',').Write(context.Background(), channelOf(foo{}, struct{ A int }{})); err == nil {
+		t.Fatalf("shouldn't be able to write values of different types")
+	}
+}
+
+type sinkFunc func(context.Context, <-chan interface{}) error
+
+func (f sinkFunc) Write(ctx context.Context, ch <-chan interface{}) error {
+	return f(ctx, ch)
+}
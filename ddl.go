@@ -0,0 +1,324 @@
+package transform
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DDLOptions are options of the table created by HiveDDL
+// they override the options in the tag of the blank field, see HiveDDL
+type DDLOptions struct {
+	// Table is the name of the table, optionally with the database, e.g. db.events
+	Table string
+	// External creates an external table
+	External bool
+	// IfNotExists creates the table only if it doesn't exist
+	IfNotExists bool
+	// StoredAs is the storage format, e.g. PARQUET or TEXTFILE
+	StoredAs string
+	// Location is the location of table's data
+	Location string
+	// Comment is the comment of the table
+	Comment string
+	// Properties are the table properties, TBLPROPERTIES
+	Properties map[string]string
+}
+
+// HiveDDL returns the CREATE TABLE statement of a Hive table with columns of the struct type,
+// which can also be an anonymous type created by NewStructExpander or NewStructCollapser
+// columns are named with GetStructFieldName, and their types are mapped from go types:
+//	1) bool, ints, floats and string to BOOLEAN, TINYINT/SMALLINT/INT/BIGINT, FLOAT/DOUBLE and STRING
+//	2) time.Time to TIMESTAMP, []byte to BINARY
+//	3) structs to STRUCT<>, slices to ARRAY<> and maps to MAP<>
+//	4) pointers to the type they point to, since all columns are nullable
+// options of a field's hive tag after the name change its column, e.g. `hive:"price,decimal(10,2),comment=in EUR"`:
+//	1) partition makes it a partition column
+//	2) comment=text sets the comment of the column, quote it if it contains commas, e.g. comment='net, in EUR'
+//	3) a Hive type is used as the column type instead of the mapped one, e.g. decimal(10,2) or date
+//	4) anything else is an error
+// options of the table are set in the hive tag of a blank field, e.g. _ struct{} `hive:"table=events,stored=parquet,external"`
+// the keys are table, stored, location, comment, external and if_not_exists, other key=value pairs are table properties
+// values can be quoted the same way as comments of columns
+func HiveDDL(typ reflect.Type, opts DDLOptions) (string, error) {
+	if typ.Kind() != reflect.Struct {
+		return "", fmt.Errorf("%s isn't a struct", typ)
+	}
+
+	var columns, partitions []string
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.Name == "_" {
+			if err := opts.fromTag(sf.Tag.Get("hive")); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue // not exported
+		}
+
+		col, err := hiveColumn(sf)
+		if err != nil {
+			return "", fmt.Errorf("field %s: %v", sf.Name, err)
+		}
+		column := fmt.Sprintf("`%s` %s", GetStructFieldName(sf), col.typ)
+		if col.comment != "" {
+			column += " COMMENT " + hiveString(col.comment)
+		}
+		if col.partition {
+			partitions = append(partitions, column)
+		} else {
+			columns = append(columns, column)
+		}
+	}
+	if opts.Table == "" {
+		return "", fmt.Errorf("table name isn't set")
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("%s doesn't have any columns which aren't partitions", typ)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CREATE ")
+	if opts.External {
+		sb.WriteString("EXTERNAL ")
+	}
+	sb.WriteString("TABLE ")
+	if opts.IfNotExists {
+		sb.WriteString("IF NOT EXISTS ")
+	}
+	fmt.Fprintf(&sb, "%s (\n  %s\n)", opts.Table, strings.Join(columns, ",\n  "))
+	if opts.Comment != "" {
+		fmt.Fprintf(&sb, "\nCOMMENT %s", hiveString(opts.Comment))
+	}
+	if len(partitions) > 0 {
+		fmt.Fprintf(&sb, "\nPARTITIONED BY (\n  %s\n)", strings.Join(partitions, ",\n  "))
+	}
+	if opts.StoredAs != "" {
+		fmt.Fprintf(&sb, "\nSTORED AS %s", strings.ToUpper(opts.StoredAs))
+	}
+	if opts.Location != "" {
+		fmt.Fprintf(&sb, "\nLOCATION %s", hiveString(opts.Location))
+	}
+	if len(opts.Properties) > 0 {
+		keys := make([]string, 0, len(opts.Properties))
+		for k := range opts.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		props := make([]string, len(keys))
+		for i, k := range keys {
+			props[i] = hiveString(k) + "=" + hiveString(opts.Properties[k])
+		}
+		fmt.Fprintf(&sb, "\nTBLPROPERTIES (%s)", strings.Join(props, ", "))
+	}
+	sb.WriteString(";\n")
+	return sb.String(), nil
+}
+
+// fromTag sets options from the tag of the blank field, unless they're already set
+func (opts *DDLOptions) fromTag(tag string) error {
+	for _, opt := range splitTagOptions(tag) {
+		key, value, _ := strings.Cut(opt, "=")
+		value = unquoteTagValue(value)
+		set := func(s *string) {
+			if *s == "" {
+				*s = value
+			}
+		}
+		switch key {
+		case "table":
+			set(&opts.Table)
+		case "stored":
+			set(&opts.StoredAs)
+		case "location":
+			set(&opts.Location)
+		case "comment":
+			set(&opts.Comment)
+		case "external":
+			opts.External = true
+		case "if_not_exists":
+			opts.IfNotExists = true
+		case "":
+			continue
+		default:
+			if !strings.Contains(opt, "=") {
+				return fmt.Errorf("unknown table option %q", opt)
+			}
+			if opts.Properties == nil {
+				opts.Properties = map[string]string{}
+			}
+			if _, ok := opts.Properties[key]; !ok {
+				opts.Properties[key] = value
+			}
+		}
+	}
+	return nil
+}
+
+// hiveColumnOptions are the options of a column, parsed from the field's hive tag
+type hiveColumnOptions struct {
+	typ       string
+	comment   string
+	partition bool
+}
+
+func hiveColumn(sf reflect.StructField) (hiveColumnOptions, error) {
+	var col hiveColumnOptions
+	opts := splitTagOptions(sf.Tag.Get("hive"))
+	if len(opts) > 0 {
+		opts = opts[1:] // name
+	}
+	for _, opt := range opts {
+		switch {
+		case opt == "partition":
+			col.partition = true
+		case strings.HasPrefix(opt, "comment="):
+			col.comment = unquoteTagValue(strings.TrimPrefix(opt, "comment="))
+		case isHiveType(opt):
+			col.typ = strings.ToUpper(opt)
+		case opt != "":
+			return col, fmt.Errorf("unknown column option %q", opt)
+		}
+	}
+	if col.typ == "" {
+		typ, err := hiveType(sf.Type)
+		if err != nil {
+			return col, err
+		}
+		col.typ = typ
+	}
+	return col, nil
+}
+
+// hiveType returns the Hive type of the go type
+func hiveType(typ reflect.Type) (string, error) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ {
+	case timeType:
+		return "TIMESTAMP", nil
+	case bytesType:
+		return "BINARY", nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "BOOLEAN", nil
+	case reflect.Int8:
+		return "TINYINT", nil
+	case reflect.Int16, reflect.Uint8:
+		return "SMALLINT", nil
+	case reflect.Int32, reflect.Uint16:
+		return "INT", nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "BIGINT", nil
+	case reflect.Uint, reflect.Uint64:
+		return "DECIMAL(20,0)", nil
+	case reflect.Float32:
+		return "FLOAT", nil
+	case reflect.Float64:
+		return "DOUBLE", nil
+	case reflect.String:
+		return "STRING", nil
+	case reflect.Slice, reflect.Array:
+		elem, err := hiveType(typ.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "ARRAY<" + elem + ">", nil
+	case reflect.Map:
+		key, err := hiveType(typ.Key())
+		if err != nil {
+			return "", err
+		}
+		elem, err := hiveType(typ.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "MAP<" + key + "," + elem + ">", nil
+	case reflect.Struct:
+		var fields []string
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			if sf.PkgPath != "" {
+				continue // not exported
+			}
+			col, err := hiveColumn(sf)
+			if err != nil {
+				return "", fmt.Errorf("%s: %v", sf.Name, err)
+			}
+			field := GetStructFieldName(sf) + ":" + col.typ
+			if col.comment != "" {
+				field += " COMMENT " + hiveString(col.comment)
+			}
+			fields = append(fields, field)
+		}
+		if len(fields) == 0 {
+			return "", fmt.Errorf("%s doesn't have any exported fields", typ)
+		}
+		return "STRUCT<" + strings.Join(fields, ",") + ">", nil
+	}
+	return "", fmt.Errorf("%s can't be mapped to a Hive type", typ)
+}
+
+// names of Hive types, complex and parametrized types are recognized by the name before < or (
+var hiveTypeNames = map[string]bool{
+	"BOOLEAN": true, "TINYINT": true, "SMALLINT": true, "INT": true, "INTEGER": true, "BIGINT": true,
+	"FLOAT": true, "DOUBLE": true, "DECIMAL": true, "NUMERIC": true,
+	"STRING": true, "VARCHAR": true, "CHAR": true, "BINARY": true, "DATE": true, "TIMESTAMP": true,
+	"ARRAY": true, "MAP": true, "STRUCT": true, "UNIONTYPE": true,
+}
+
+// isHiveType checks if the tag option is a Hive type, e.g. date, decimal(10,2) or array<int>
+func isHiveType(opt string) bool {
+	name := opt
+	if i := strings.IndexAny(name, "(<"); i >= 0 {
+		name = name[:i]
+	}
+	return hiveTypeNames[strings.ToUpper(strings.TrimSpace(name))]
+}
+
+// splitTagOptions splits the tag by commas which aren't inside of parentheses or quoted values,
+// so that `price,decimal(10,2),comment='net, in EUR'` is split into price, decimal(10,2) and comment='net, in EUR'
+// a value is quoted if it starts with ' right after =, and it ends with the next '
+func splitTagOptions(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	var opts []string
+	depth, start, quoted := 0, 0, false
+	for i, r := range tag {
+		switch {
+		case r == '\'' && (quoted || (i > 0 && tag[i-1] == '=')):
+			quoted = !quoted
+		case quoted:
+		case r == '(' || r == '<':
+			depth++
+		case r == ')' || r == '>':
+			depth--
+		case r == ',':
+			if depth == 0 {
+				opts = append(opts, strings.TrimSpace(tag[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(opts, strings.TrimSpace(tag[start:]))
+}
+
+// unquoteTagValue removes the quotes of a quoted tag value, see splitTagOptions
+func unquoteTagValue(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// hiveString quotes s as a Hive string literal
+func hiveString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
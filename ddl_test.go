@@ -0,0 +1,135 @@
+package transform
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHiveDDL(t *testing.T) {
+	type item struct {
+		SKU   string `hive:"sku_id"`
+		Count uint16
+	}
+	type order struct {
+		_       struct{} `hive:"table=sales.orders,stored=parquet,external,location=s3://bucket/orders,parquet.compression=SNAPPY"`
+		ID      int64    `hive:"order_id,comment=it's unique"`
+		Country string   `hive:"country,char(2),comment='ISO code, upper case'"`
+		Price   float64  `hive:"price,decimal(10,2)"`
+		Items   []item
+		Tags    map[string]*bool
+		Created time.Time
+		Raw     []byte
+		Day     string `hive:"dt,partition"`
+		hidden  int
+	}
+
+	collapser, err := NewStructCollapser(reflect.TypeOf(order{}), []string{"price", "dt", "items"})
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+
+	cases := []struct {
+		typ  reflect.Type
+		opts DDLOptions
+		ddl  string
+		err  bool
+	}{
+		{
+			typ: reflect.TypeOf(order{}),
+			ddl: "CREATE EXTERNAL TABLE sales.orders (\n" +
+				"  `order_id` BIGINT COMMENT 'it\\'s unique',\n" +
+				"  `country` CHAR(2) COMMENT 'ISO code, upper case',\n" +
+				"  `price` DECIMAL(10,2),\n" +
+				"  `items` ARRAY<STRUCT<sku_id:STRING,count:INT>>,\n" +
+				"  `tags` MAP<STRING,BOOLEAN>,\n" +
+				"  `created` TIMESTAMP,\n" +
+				"  `raw` BINARY\n" +
+				")\n" +
+				"PARTITIONED BY (\n" +
+				"  `dt` STRING\n" +
+				")\n" +
+				"STORED AS PARQUET\n" +
+				"LOCATION 's3://bucket/orders'\n" +
+				"TBLPROPERTIES ('parquet.compression'='SNAPPY');\n",
+		},
+		{
+			typ:  OutputType(collapser),
+			opts: DDLOptions{Table: "prices", IfNotExists: true, StoredAs: "textfile", Comment: "projection"},
+			ddl: "CREATE TABLE IF NOT EXISTS prices (\n" +
+				"  `price` DECIMAL(10,2),\n" +
+				"  `items` ARRAY<STRUCT<sku_id:STRING,count:INT>>\n" +
+				")\n" +
+				"COMMENT 'projection'\n" +
+				"PARTITIONED BY (\n" +
+				"  `dt` STRING\n" +
+				")\n" +
+				"STORED AS TEXTFILE;\n",
+		},
+		{
+			typ: OutputType(collapser),
+			err: true, // no table name
+		},
+		{
+			typ:  reflect.TypeOf(struct{ C chan int }{}),
+			opts: DDLOptions{Table: "t"},
+			err:  true,
+		},
+		{
+			typ: reflect.TypeOf(struct {
+				_ struct{} `hive:"table=t,compressed"`
+				A int
+			}{}),
+			err: true, // unknown table option
+		},
+		{
+			typ: reflect.TypeOf(struct {
+				A int `hive:"a,partition"`
+			}{}),
+			opts: DDLOptions{Table: "t"},
+			err:  true, // only partitions
+		},
+		{
+			typ: reflect.TypeOf(struct {
+				A int `hive:"a,partiton"`
+			}{}),
+			opts: DDLOptions{Table: "t"},
+			err:  true, // unknown column option
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			ddl, err := HiveDDL(c.typ, c.opts)
+			if err != nil {
+				if !c.err {
+					t.Fatalf("can't create DDL: %v", err)
+				}
+				return
+			}
+			if c.err {
+				t.Fatalf("shouldn't be able to create DDL:\n%s", ddl)
+			}
+			if ddl != c.ddl {
+				t.Fatalf("DDL mismatch\n\thave:\n%s\n\twant:\n%s", ddl, c.ddl)
+			}
+		})
+	}
+}
+
+func TestSplitTagOptions(t *testing.T) {
+	for tag, expect := range map[string][]string{
+		"":                                {},
+		"price":                           {"price"},
+		"price,decimal(10,2), partition":  {"price", "decimal(10,2)", "partition"},
+		",map<string,array<int>>,comment": {"", "map<string,array<int>>", "comment"},
+		"a,comment='x, (y',partition":     {"a", "comment='x, (y'", "partition"},
+		"a,comment=it's, b":               {"a", "comment=it's", "b"},
+	} {
+		if opts := splitTagOptions(tag); strings.Join(opts, "|") != strings.Join(expect, "|") {
+			t.Fatalf("options of %q mismatch\n\thave:\t%q\n\twant:\t%q", tag, opts, expect)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package transform
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DeadLetter is an input which failed to transform
+type DeadLetter struct {
+	// Input is the value which failed to transform
+	Input interface{}
+	// Err is the error transforming it returned
+	Err error
+	// Time is when transforming failed
+	Time time.Time
+}
+
+// DeadLetterSink receives dead letters, it must be safe for concurrent use
+type DeadLetterSink interface {
+	// Send stores the dead letter, ctx.Done() should be monitored
+	Send(context.Context, DeadLetter) error
+}
+
+// WithDeadLetter wraps the given transformer in a way that inputs which failed to transform are sent to the sink
+// transform fails only if sending to the sink fails
+// outputs written before transform failed are not taken back, use WithRetry to buffer them if that's a problem
+// errors caused by cancelling the context aren't dead letters, they're returned as is
+func WithDeadLetter(t Transformer, sink DeadLetterSink) Transformer {
+	return WithInputErrorHandler(t, func(ctx context.Context, v interface{}, err error) error {
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+			return err
+		}
+		return sink.Send(ctx, DeadLetter{Input: v, Err: err, Time: time.Now()})
+	})
+}
+
+// ChannelSink returns a sink which sends dead letters to the given channel
+func ChannelSink(ch chan<- DeadLetter) DeadLetterSink {
+	return channelSink(ch)
+}
+
+type channelSink chan<- DeadLetter
+
+// Send is part of the DeadLetterSink interface
+func (s channelSink) Send(ctx context.Context, dl DeadLetter) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case s <- dl:
+		return nil
+	}
+}
+
+// MemorySink is a sink which keeps all dead letters in memory
+// zero value is ready to use
+type MemorySink struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+// Send is part of the DeadLetterSink interface
+func (s *MemorySink) Send(_ context.Context, dl DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, dl)
+	return nil
+}
+
+// DeadLetters returns all dead letters received so far
+func (s *MemorySink) DeadLetters() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeadLetter(nil), s.letters...)
+}
+
+// jsonDeadLetter is how a dead letter is stored in JSON Lines
+type jsonDeadLetter struct {
+	Time  time.Time       `json:"time"`
+	Error string          `json:"error"`
+	Path  []string        `json:"path,omitempty"`
+	Input json.RawMessage `json:"input"`
+}
+
+// JSONLSink is a sink which writes dead letters as JSON Lines, one JSON object per line
+type JSONLSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLSink creates a sink which writes dead letters to w, which is usually a file
+// use ReadDeadLetters to read them back
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w}
+}
+
+// Send is part of the DeadLetterSink interface
+func (s *JSONLSink) Send(_ context.Context, dl DeadLetter) error {
+	input, err := json.Marshal(dl.Input)
+	if err != nil {
+		return fmt.Errorf("can't encode input %T: %v", dl.Input, err)
+	}
+	jdl := jsonDeadLetter{Time: dl.Time, Error: dl.Err.Error(), Input: input}
+	var te *TransformError
+	if errors.As(dl.Err, &te) {
+		jdl.Path = te.Path
+	}
+
+	line, err := json.Marshal(jdl)
+	if err != nil {
+		return fmt.Errorf("can't encode dead letter: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// ReadDeadLetters reads dead letters written by a JSONLSink
+// inputs are decoded into new values of type typ, and errors only keep their message
+func ReadDeadLetters(r io.Reader, typ reflect.Type) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for line := 1; scanner.Scan(); line++ {
+		var jdl jsonDeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &jdl); err != nil {
+			return nil, fmt.Errorf("line %d: can't decode dead letter: %v", line, err)
+		}
+		input := reflect.New(typ)
+		if err := json.Unmarshal(jdl.Input, input.Interface()); err != nil {
+			return nil, fmt.Errorf("line %d: can't decode input into %s: %v", line, typ, err)
+		}
+		letters = append(letters, DeadLetter{
+			Input: input.Elem().Interface(),
+			Err:   errors.New(jdl.Error),
+			Time:  jdl.Time,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// Replay transforms inputs of all dead letters with the given transformer and sends them to the output channel
+// it behaves the same as All, so wrap the transformer with WithDeadLetter to catch inputs which fail again
+func Replay(ctx context.Context, t Transformer, letters []DeadLetter, outCh chan<- interface{}) error {
+	// cancel stops the feeding goroutine if All returns early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	inCh := make(chan interface{})
+	go func() {
+		defer close(inCh)
+		for _, dl := range letters {
+			select {
+			case <-ctx.Done():
+				return
+			case inCh <- dl.Input:
+			}
+		}
+	}()
+	return All(ctx, t, inCh, outCh)
+}
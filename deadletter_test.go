@@ -0,0 +1,150 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type deadLetterTestUser struct {
+	Name string
+	Age  int
+}
+
+func TestWithDeadLetter(t *testing.T) {
+	adult, _ := FromFunction(func(u deadLetterTestUser) (string, error) {
+		if u.Age < 18 {
+			return "", errors.New("too young")
+		}
+		return u.Name, nil
+	})
+	users := []interface{}{
+		deadLetterTestUser{"ana", 30},
+		deadLetterTestUser{"ivo", 12},
+		deadLetterTestUser{"eva", 40},
+		deadLetterTestUser{"max", 5},
+	}
+
+	var memory MemorySink
+	var buf bytes.Buffer
+	ch := make(chan DeadLetter, len(users))
+
+	for _, sink := range []DeadLetterSink{&memory, NewJSONLSink(&buf), ChannelSink(ch)} {
+		outs := streamOutputs(t, WithDeadLetter(adult, sink), users...)
+		if want := []interface{}{"ana", "eva"}; !reflect.DeepEqual(outs, want) {
+			t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+		}
+	}
+	close(ch)
+
+	fromJSONL, err := ReadDeadLetters(&buf, reflect.TypeOf(deadLetterTestUser{}))
+	if err != nil {
+		t.Fatalf("can't read dead letters: %v", err)
+	}
+	var fromChannel []DeadLetter
+	for dl := range ch {
+		fromChannel = append(fromChannel, dl)
+	}
+
+	want := []interface{}{users[1], users[3]}
+	for _, letters := range [][]DeadLetter{memory.DeadLetters(), fromJSONL, fromChannel} {
+		var inputs []interface{}
+		for _, dl := range letters {
+			if dl.Err == nil || dl.Time.IsZero() {
+				t.Fatalf("dead letter is missing error or time: %+v", dl)
+			}
+			inputs = append(inputs, dl.Input)
+		}
+		if !reflect.DeepEqual(inputs, want) {
+			t.Fatalf("dead letters mismatch:\n\thave: %v\n\twant: %v", inputs, want)
+		}
+	}
+
+	// replay them after everyone grew up
+	older, _ := FromFunction(func(u deadLetterTestUser) deadLetterTestUser {
+		u.Age += 10
+		return u
+	})
+	replay, err := Chain(older, adult)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	outCh := make(chan interface{})
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(outCh)
+		errCh <- Replay(context.Background(), replay, fromJSONL, outCh)
+	}()
+	var outs []interface{}
+	for out := range outCh {
+		outs = append(outs, out)
+	}
+	if err := <-errCh; err == nil {
+		t.Fatalf("replay should error out, max is still too young")
+	}
+	if want := []interface{}{"ivo"}; !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+}
+
+func TestWithInputErrorHandler(t *testing.T) {
+	ht := handlerTest{}
+	var input interface{}
+	tr := WithInputErrorHandler(ht, func(_ context.Context, v interface{}, err error) error {
+		input = v
+		return nil
+	})
+
+	if err := tr.Transform(context.Background(), 42, nil); err != nil {
+		t.Fatalf("error was returned, but shouldn't have been")
+	}
+	if input != 42 {
+		t.Fatalf("handler got %v, expecting %v", input, 42)
+	}
+}
+
+func TestReplayError(t *testing.T) {
+	fail, _ := FromFunction(func(u deadLetterTestUser) (deadLetterTestUser, error) {
+		return u, errors.New("fail")
+	})
+	letters := make([]DeadLetter, 10)
+	for i := range letters {
+		letters[i] = DeadLetter{Input: deadLetterTestUser{Age: i}}
+	}
+
+	before := runtime.NumGoroutine()
+	if err := Replay(context.Background(), fail, letters, make(chan interface{})); err == nil {
+		t.Fatalf("replay should fail")
+	}
+	// the goroutine feeding letters should exit
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked: %d before, %d after", before, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDeadLetterCanceled(t *testing.T) {
+	canceled, _ := FromFunction(func(ctx context.Context, u deadLetterTestUser) (deadLetterTestUser, error) {
+		<-ctx.Done()
+		return u, ctx.Err()
+	})
+	var sink MemorySink
+	tr := WithDeadLetter(canceled, &sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := tr.Transform(ctx, deadLetterTestUser{}, make(chan interface{}))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expecting context.Canceled, got %v", err)
+	}
+	if letters := sink.DeadLetters(); len(letters) != 0 {
+		t.Fatalf("cancellation shouldn't be a dead letter, got %v", letters)
+	}
+}
@@ -0,0 +1,240 @@
+package transform
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Node describes a transformer and the transformers it's built from
+type Node struct {
+	// Kind is the kind of the transformer, e.g. "chain" or "function"
+	Kind string
+	// Name is the name given to the transformer with Named, or a Registry
+	Name string
+	// Stage is the name of the transformer inside of its parent, e.g. "chain[1]", it's empty for the root
+	Stage string
+	// InputType is the input type of the transformer
+	InputType reflect.Type
+	// OutputType is the output type of the transformer, nil if it's not known
+	OutputType reflect.Type
+	// Fields are field mappings of struct expanders and collapsers
+	Fields []FieldMapping
+	// Attrs are other details about the transformer, e.g. options of a chain stage
+	Attrs map[string]string
+	// Children are the transformers this one is built from, or wraps
+	Children []*Node
+}
+
+// FieldMapping describes which field of the input struct is copied to which field of the output struct
+type FieldMapping struct {
+	// Name of the field, as returned by GetStructFieldName
+	Name string
+	// Input is the index of the field in the input struct
+	Input int
+	// Output is the index of the field in the output struct
+	Output int
+}
+
+// Describe returns the tree of nodes which describes the given transformer
+func Describe(t Transformer) *Node {
+	return describe(t, "")
+}
+
+func describe(t Transformer, stage string) *Node {
+	if nt, ok := asNamed(t); ok {
+		// a name isn't a separate node, it's shown on the node it names
+		n := describe(nt.Transformer, stage)
+		n.Name = nt.name
+		return n
+	}
+
+	n := &Node{
+		Stage:      stage,
+		InputType:  t.InputType(),
+		OutputType: OutputType(t),
+		Attrs:      map[string]string{},
+	}
+
+	// wrapped is the transformer a wrapper wraps
+	var wrapped Transformer
+	switch tt := t.(type) {
+	case chain:
+		n.Kind = "chain"
+		for i, s := range tt {
+			child := describe(s.Transformer, tt.stage(i))
+			if s.Workers > 1 {
+				child.Attrs["workers"] = strconv.Itoa(s.Workers)
+				child.Attrs["ordered"] = strconv.FormatBool(s.Ordered)
+			}
+			if s.Buffer > 0 {
+				child.Attrs["buffer"] = strconv.Itoa(s.Buffer)
+			}
+			n.Children = append(n.Children, child)
+		}
+	case parallel:
+		n.Kind = "parallel"
+		n.Children = describeChildren(tt)
+	case *router:
+		n.Kind = "route"
+		n.Children = describeChildren(tt)
+	case *function:
+		n.Kind = "function"
+		n.Attrs["func"] = tt.f.Type().String()
+	case *structTransformer:
+		n.Kind = "struct expander"
+		if tt.idxReversed {
+			n.Kind = "struct collapser"
+		}
+		for i, j := range tt.idx {
+			m := FieldMapping{Input: i, Output: j}
+			if tt.idxReversed {
+				m.Input, m.Output = j, i
+			}
+			m.Name = GetStructFieldName(tt.inputType.Field(m.Input))
+			n.Fields = append(n.Fields, m)
+		}
+	case *batcher:
+		n.Kind = "batch"
+		n.Attrs["size"] = strconv.Itoa(tt.size)
+		if tt.maxWait > 0 {
+			n.Attrs["max wait"] = tt.maxWait.String()
+		}
+	case unbatcher:
+		n.Kind = "unbatch"
+	case *windower:
+		n.Kind = "window"
+		n.Attrs["size"] = tt.size.String()
+		n.Attrs["slide"] = tt.slide.String()
+	case discard:
+		n.Kind = "discard"
+	case partitioned:
+		n.Kind = "partitioned"
+		n.Attrs["workers"] = strconv.Itoa(tt.n)
+		wrapped = tt.Transformer
+	case errorHandlingTransformer:
+		n.Kind = "error handler"
+		wrapped = tt.Transformer
+	case retryingTransformer:
+		n.Kind = "retry"
+		if tt.policy.MaxAttempts > 0 {
+			n.Attrs["max attempts"] = strconv.Itoa(tt.policy.MaxAttempts)
+		}
+		wrapped = tt.Transformer
+	case recoveringTransformer:
+		n.Kind = "recover"
+		wrapped = tt.Transformer
+	case recoveringStreamer:
+		n.Kind = "recover"
+		wrapped = tt.Transformer
+	case timeoutTransformer:
+		n.Kind = "timeout"
+		n.Attrs["timeout"] = tt.timeout.String()
+		wrapped = tt.Transformer
+	case instrumentedTransformer:
+		n.Kind = "instrumented"
+		wrapped = tt.Transformer
+	case instrumentedStreamer:
+		n.Kind = "instrumented"
+		wrapped = tt.Transformer
+	case streamerMisuse:
+		n.Kind = "streamer misuse"
+		wrapped = tt.Transformer
+	default:
+		n.Kind = fmt.Sprintf("%T", t)
+	}
+
+	if wrapped != nil {
+		n.Children = []*Node{describe(wrapped, "")}
+	}
+	return n
+}
+
+func describeChildren(c composite) []*Node {
+	var nodes []*Node
+	for i, child := range c.children() {
+		nodes = append(nodes, describe(child, c.stage(i)))
+	}
+	return nodes
+}
+
+// label returns a multiline description of the node, without its children
+func (n *Node) label() []string {
+	title := n.Kind
+	if n.Name != "" {
+		title = fmt.Sprintf("%s (%s)", n.Name, n.Kind)
+	}
+	if n.Stage != "" {
+		title = n.Stage + ": " + title
+	}
+	out := "?"
+	if n.OutputType != nil {
+		out = n.OutputType.String()
+	}
+	lines := []string{title, n.InputType.String() + " → " + out}
+
+	for _, f := range n.Fields {
+		lines = append(lines, fmt.Sprintf("%s: %d → %d", f.Name, f.Input, f.Output))
+	}
+
+	keys := make([]string, 0, len(n.Attrs))
+	for k := range n.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lines = append(lines, k+"="+n.Attrs[k])
+	}
+	return lines
+}
+
+// walk calls f for every node in the tree, with ids assigned in depth-first order
+// f is called for a node before its children
+func (n *Node) walk(f func(id int, n *Node, parent int)) {
+	next := 0
+	var visit func(n *Node, parent int)
+	visit = func(n *Node, parent int) {
+		id := next
+		next++
+		f(id, n, parent)
+		for _, child := range n.Children {
+			visit(child, id)
+		}
+	}
+	visit(n, -1)
+}
+
+// DOT renders the tree as a Graphviz digraph
+// every node is a box, and there is an edge from every node to each of its children
+func (n *Node) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph transform {\n\tnode [shape=box];\n")
+	n.walk(func(id int, n *Node, parent int) {
+		label := strings.Join(n.label(), `\n`)
+		label = strings.ReplaceAll(label, `"`, `\"`)
+		fmt.Fprintf(&sb, "\tn%d [label=\"%s\"];\n", id, label)
+		if parent >= 0 {
+			fmt.Fprintf(&sb, "\tn%d -> n%d;\n", parent, id)
+		}
+	})
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the tree as a Mermaid flowchart
+// every node is a box, and there is an edge from every node to each of its children
+func (n *Node) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+	n.walk(func(id int, n *Node, parent int) {
+		label := strings.Join(n.label(), "<br/>")
+		label = strings.ReplaceAll(label, `"`, "#quot;")
+		fmt.Fprintf(&sb, "\tn%d[\"%s\"]\n", id, label)
+		if parent >= 0 {
+			fmt.Fprintf(&sb, "\tn%d --> n%d\n", parent, id)
+		}
+	})
+	return sb.String()
+}
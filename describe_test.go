@@ -0,0 +1,73 @@
+package transform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	type foo struct {
+		I int
+		B bool
+		S string
+	}
+
+	double, err := FromFunction(func(f foo) foo { f.I *= 2; return f })
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	p, err := InParallel(double, double)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	c, err := NewStructCollapser(reflect.TypeOf(foo{}), []string{"s", "i"})
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	tr, err := ChainWithOptions(Stage{Transformer: p}, Stage{Transformer: c, Workers: 2, Ordered: true})
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+
+	root := Describe(tr)
+	if root.Kind != "chain" || len(root.Children) != 2 {
+		t.Fatalf("unexpected root: %+v", root)
+	}
+	if root.InputType != reflect.TypeOf(foo{}) || root.OutputType != OutputType(c) {
+		t.Fatalf("unexpected root types: %v → %v", root.InputType, root.OutputType)
+	}
+
+	par := root.Children[0]
+	if par.Kind != "parallel" || par.Stage != "chain[0]" || len(par.Children) != 2 {
+		t.Fatalf("unexpected parallel node: %+v", par)
+	}
+	for i, child := range par.Children {
+		if child.Kind != "function" || child.Stage != (parallel{}).stage(i) {
+			t.Fatalf("unexpected function node: %+v", child)
+		}
+	}
+
+	col := root.Children[1]
+	if col.Kind != "struct collapser" || col.Attrs["workers"] != "2" || col.Attrs["ordered"] != "true" {
+		t.Fatalf("unexpected collapser node: %+v", col)
+	}
+	expect := []FieldMapping{{Name: "s", Input: 2, Output: 0}, {Name: "i", Input: 0, Output: 1}}
+	if !reflect.DeepEqual(col.Fields, expect) {
+		t.Fatalf("field mappings mismatch\n\thave:\t%+v\n\twant:\t%+v", col.Fields, expect)
+	}
+
+	dot := root.DOT()
+	for _, s := range []string{"digraph transform {", `n0 [label="chain\n`, "n0 -> n1;", "n1 -> n2;", "n0 -> n4;", `s: 2 → 0`} {
+		if !strings.Contains(dot, s) {
+			t.Fatalf("DOT output doesn't contain %q:\n%s", s, dot)
+		}
+	}
+
+	mermaid := root.Mermaid()
+	for _, s := range []string{"flowchart TD", `n1["chain[0]: parallel<br/>`, "n0 --> n4", `i: 0 → 1`} {
+		if !strings.Contains(mermaid, s) {
+			t.Fatalf("Mermaid output doesn't contain %q:\n%s", s, mermaid)
+		}
+	}
+}
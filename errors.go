@@ -0,0 +1,61 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RedactInput is applied to every input before it's stored in a TransformError
+// replace it to keep sensitive values out of errors and logs
+var RedactInput = func(v interface{}) interface{} {
+	return v
+}
+
+// TransformError is returned when transforming a value fails
+type TransformError struct {
+	// Path is the path to the failed stage inside of nested transformers,
+	// e.g. [chain[1] parallel[0]] is the first transformer of InParallel which is the second stage of a Chain
+	// it's empty if the transformer which failed wasn't inside of any other transformer
+	Path []string
+	// Input is the value which failed to transform, as returned by RedactInput
+	Input interface{}
+	// Err is the cause of the error
+	Err error
+}
+
+// Error is part of the error interface
+func (e *TransformError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("transform(%+v): %v", e.Input, e.Err)
+	}
+	return fmt.Sprintf("transform(%+v) at %s: %v", e.Input, strings.Join(e.Path, "/"), e.Err)
+}
+
+// Unwrap returns the cause of the error
+func (e *TransformError) Unwrap() error {
+	return e.Err
+}
+
+// newTransformError wraps the error of transforming v into a TransformError
+// if err already is a TransformError, it's returned as is, since it was created closer to the cause
+func newTransformError(v interface{}, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*TransformError); ok {
+		return err
+	}
+	return &TransformError{Input: RedactInput(v), Err: err}
+}
+
+// withStage prepends the stage to the path of a TransformError
+// other errors (e.g. context errors) are returned as is
+func withStage(stage string, err error) error {
+	te, ok := err.(*TransformError)
+	if !ok {
+		return err
+	}
+	withPath := *te
+	withPath.Path = append([]string{stage}, te.Path...)
+	return &withPath
+}
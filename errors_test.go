@@ -0,0 +1,76 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransformError(t *testing.T) {
+	errNegative := errors.New("negative")
+
+	inc, _ := FromFunction(func(x int) int { return x + 1 })
+	check, _ := FromFunction(func(x int) (int, error) {
+		if x < 0 {
+			return 0, errNegative
+		}
+		return x, nil
+	})
+	dec, _ := FromFunction(func(x int) int { return x - 1 })
+	double, _ := FromFunction(func(x int) int { return 2 * x })
+
+	par, err := InParallel(double, check)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	inner, err := Chain(dec, par)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+	tr, err := Chain(inc, inner)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	_, err = collectOutputs(t, tr, -5)
+	if !errors.Is(err, errNegative) {
+		t.Fatalf("expecting error to wrap %v, got %v", errNegative, err)
+	}
+
+	var te *TransformError
+	if !errors.As(err, &te) {
+		t.Fatalf("expecting a TransformError, got %T", err)
+	}
+	if want := []string{"chain[1]", "chain[1]", "parallel[1]"}; !reflect.DeepEqual(te.Path, want) {
+		t.Fatalf("path mismatch:\n\thave: %v\n\twant: %v", te.Path, want)
+	}
+	// -5 is incremented, then decremented before it gets to check
+	if te.Input != -5 {
+		t.Fatalf("input mismatch:\n\thave: %v\n\twant: %v", te.Input, -5)
+	}
+	if strings.Count(err.Error(), "transform") != 1 {
+		t.Fatalf("error message shouldn't be nested: %v", err)
+	}
+}
+
+func TestRedactInput(t *testing.T) {
+	defer func(redact func(interface{}) interface{}) { RedactInput = redact }(RedactInput)
+	RedactInput = func(interface{}) interface{} { return "<redacted>" }
+
+	check, _ := FromFunction(func(s string) (string, error) { return "", errors.New("invalid") })
+
+	inCh := make(chan interface{}, 1)
+	inCh <- "secret"
+	close(inCh)
+
+	err := All(context.Background(), check, inCh, make(chan interface{}))
+	var te *TransformError
+	if !errors.As(err, &te) {
+		t.Fatalf("expecting a TransformError, got %v", err)
+	}
+	if te.Input != "<redacted>" || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("input wasn't redacted: %v", err)
+	}
+}
@@ -6,22 +6,54 @@ import (
 	"reflect"
 )
 
+// outputKind determines how the function's outputs are written to the channel
+type outputKind int
+
+const (
+	// single value: func(any) any
+	outputValue outputKind = iota
+	// the input is written if the function returns true: func(any) bool
+	outputFilter
+	// the output is written if the function returns true: func(any) (any, bool)
+	outputOptional
+	// every element of the output is written: func(any) []any
+	outputSlice
+	// function writes the outputs itself: func(ctx, any, emit) error
+	outputEmit
+)
+
 type function struct {
 	// functions input type
 	inputType reflect.Type
+	// functions output type
+	outputType reflect.Type
 	// function
 	f reflect.Value
 	// does the input need context
 	inputContext bool
 	// does the output contain an error
 	outputError bool
+	// how the outputs are written to the channel
+	output outputKind
+	// type of the emit function, only used for outputEmit
+	emitType reflect.Type
 }
 
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
 // InputType is part of the Transformer interface
 func (t *function) InputType() reflect.Type {
 	return t.inputType
 }
 
+// OutputType is part of the OutputTyper interface
+func (t *function) OutputType() reflect.Type {
+	return t.outputType
+}
+
 // Transform is part of the Transformer interface
 func (t *function) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
 	in := []reflect.Value{reflect.ValueOf(v)}
@@ -29,19 +61,50 @@ func (t *function) Transform(ctx context.Context, v interface{}, ch chan<- inter
 		// prepend context
 		in = append([]reflect.Value{reflect.ValueOf(ctx)}, in[0])
 	}
+	if t.output == outputEmit {
+		// append the emit function, which sends to the channel
+		emit := reflect.MakeFunc(t.emitType, func(args []reflect.Value) []reflect.Value {
+			err := send(ctx, ch, args[0].Interface())
+			return []reflect.Value{reflect.ValueOf(&err).Elem()}
+		})
+		in = append(in, emit)
+	}
 
 	out := t.f.Call(in)
 	if t.outputError {
-		// check if error occurred, it's the second field in output
-		if !out[1].IsNil() {
-			return out[1].Interface().(error)
+		// check if error occurred, it's the last field in output
+		if errValue := out[len(out)-1]; !errValue.IsNil() {
+			return newTransformError(v, errValue.Interface().(error))
 		}
 	}
 
+	switch t.output {
+	case outputFilter:
+		if out[0].Bool() {
+			return send(ctx, ch, v)
+		}
+	case outputOptional:
+		if out[1].Bool() {
+			return send(ctx, ch, out[0].Interface())
+		}
+	case outputSlice:
+		for i, n := 0, out[0].Len(); i < n; i++ {
+			if err := send(ctx, ch, out[0].Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	case outputValue:
+		return send(ctx, ch, out[0].Interface())
+	}
+	return nil
+}
+
+// send writes the value to the channel, unless the context is done first
+func send(ctx context.Context, ch chan<- interface{}, v interface{}) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case ch <- out[0].Interface():
+	case ch <- v:
 		return nil
 	}
 }
@@ -55,6 +118,12 @@ func (t *function) Transform(ctx context.Context, v interface{}, ch chan<- inter
 //	1) func(any) any
 //	2) func(any) (any, error)
 //	3) func(ctx, any) (any, error)
+//
+// some signatures don't output exactly one value for each input:
+//	4) func(any) bool - filter, input is written to the channel only if the function returns true
+//	5) func(any) (any, bool) - output is written to the channel only if the function returns true
+//	6) func(any) []any - flat map, every element of the slice is written to the channel ([]byte is a single value)
+//	7) func(ctx, any, emit func(any) error) error - function can call emit as many times as it wants
 func FromFunction(v interface{}) (Transformer, error) {
 	f := reflect.ValueOf(v)
 	t := f.Type()
@@ -65,20 +134,35 @@ func FromFunction(v interface{}) (Transformer, error) {
 
 	transformer := &function{f: f}
 
-	errorType := reflect.TypeOf((*error)(nil)).Elem()
-	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
-
 	switch t.NumIn() {
 	case 1:
 		transformer.inputType = t.In(0)
-	case 2:
+	case 2, 3:
 		if !t.In(0).Implements(contextType) {
 			return nil, fmt.Errorf("first argument must implement context.Context, got %s", t.In(0))
 		}
 		transformer.inputType = t.In(1)
 		transformer.inputContext = true
 	default:
-		return nil, fmt.Errorf("function can have either 1 (any) or 2 (ctx, any) input arguments, got %d", t.NumIn())
+		return nil, fmt.Errorf("function can have either 1 (any), 2 (ctx, any) or 3 (ctx, any, emit) input arguments, got %d", t.NumIn())
+	}
+
+	if t.NumIn() == 3 {
+		emitType := t.In(2)
+		if emitType.Kind() != reflect.Func || emitType.NumIn() != 1 || emitType.NumOut() != 1 || emitType.Out(0) != errorType {
+			return nil, fmt.Errorf("third argument must be an emit function func(any) error, got %s", emitType)
+		}
+		if t.NumOut() != 1 {
+			return nil, fmt.Errorf("function with an emit argument can only have an error output, got %d outputs", t.NumOut())
+		}
+		if !t.Out(0).Implements(errorType) {
+			return nil, fmt.Errorf("function with an emit argument must output error, got %s", t.Out(0))
+		}
+		transformer.outputType = emitType.In(0)
+		transformer.outputError = true
+		transformer.output = outputEmit
+		transformer.emitType = emitType
+		return transformer, nil
 	}
 
 	switch t.NumOut() {
@@ -86,13 +170,32 @@ func FromFunction(v interface{}) (Transformer, error) {
 		if transformer.inputContext {
 			return nil, fmt.Errorf("function takes in context as input, but doesn't have error in it's output")
 		}
+		out := t.Out(0)
+		switch {
+		case out.Kind() == reflect.Bool:
+			transformer.outputType = transformer.inputType
+			transformer.output = outputFilter
+		case out.Kind() == reflect.Slice && out.Elem().Kind() != reflect.Uint8:
+			transformer.outputType = out.Elem()
+			transformer.output = outputSlice
+		default:
+			transformer.outputType = out
+		}
 	case 2:
-		if !t.Out(1).Implements(errorType) {
-			return nil, fmt.Errorf("second output must implement error, got %s", t.Out(1))
+		switch {
+		case t.Out(1).Implements(errorType):
+			transformer.outputError = true
+		case t.Out(1).Kind() == reflect.Bool:
+			if transformer.inputContext {
+				return nil, fmt.Errorf("function takes in context as input, but outputs bool instead of error")
+			}
+			transformer.output = outputOptional
+		default:
+			return nil, fmt.Errorf("second output must implement error or be a bool, got %s", t.Out(1))
 		}
-		transformer.outputError = true
+		transformer.outputType = t.Out(0)
 	default:
-		return nil, fmt.Errorf("function can have either 1 (any) or 2 (any, error) outputs, got %d", t.NumOut())
+		return nil, fmt.Errorf("function can have either 1 (any) or 2 (any, error) or (any, bool) outputs, got %d", t.NumOut())
 	}
 
 	return transformer, nil
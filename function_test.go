@@ -51,6 +51,14 @@ func TestFunction(t *testing.T) {
 		{f: func(context.Context, int) int { return 0 }, createErr: true},
 		// only one output arg, no error
 		{f: func(int) (int, int) { return 0, 0 }, createErr: true},
+		// bool output with context, instead of error
+		{f: func(context.Context, int) (int, bool) { return 0, false }, createErr: true},
+		// emit function must output error
+		{f: func(context.Context, int, func(int)) error { return nil }, createErr: true},
+		// function with emit must output only error
+		{f: func(context.Context, int, func(int) error) (int, error) { return 0, nil }, createErr: true},
+		// 3 arguments without context
+		{f: func(int, int, func(int) error) error { return nil }, createErr: true},
 		{f: square, in: 2, out: 4},
 		{f: root, in: 25, out: 5},
 		{f: root, in: -25, transformErr: true},
@@ -98,3 +106,77 @@ func TestFunction(t *testing.T) {
 		})
 	}
 }
+
+func TestFunctionOutputs(t *testing.T) {
+	even := func(x int) bool {
+		return x%2 == 0
+	}
+
+	half := func(x int) (int, bool) {
+		return x / 2, x%2 == 0
+	}
+
+	repeat := func(x int) []int {
+		out := make([]int, x)
+		for i := range out {
+			out[i] = x
+		}
+		return out
+	}
+
+	countdown := func(ctx context.Context, x int, emit func(int) error) error {
+		if x < 0 {
+			return fmt.Errorf("negative x")
+		}
+		for i := x; i > 0; i-- {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, c := range []struct {
+		f            interface{}
+		in           interface{}
+		outs         []interface{}
+		outputType   reflect.Type
+		transformErr bool
+	}{
+		{f: even, in: 2, outs: []interface{}{2}, outputType: reflect.TypeOf(1)},
+		{f: even, in: 3},
+		{f: half, in: 8, outs: []interface{}{4}, outputType: reflect.TypeOf(1)},
+		{f: half, in: 7},
+		{f: repeat, in: 3, outs: []interface{}{3, 3, 3}, outputType: reflect.TypeOf(1)},
+		{f: repeat, in: 0},
+		{f: countdown, in: 3, outs: []interface{}{3, 2, 1}, outputType: reflect.TypeOf(1)},
+		{f: countdown, in: -1, transformErr: true},
+		// byte slices are a single value
+		{f: func(s string) []byte { return []byte(s) }, in: "ab", outs: []interface{}{[]byte("ab")}, outputType: reflect.TypeOf([]byte{})},
+	} {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			tr, err := FromFunction(c.f)
+			if err != nil {
+				t.Fatalf("should be able to create transformer from %T: %v", c.f, err)
+			}
+			if c.outputType != nil && OutputType(tr) != c.outputType {
+				t.Fatalf("output type mismatch:\n\thave: %v\n\twant: %v", OutputType(tr), c.outputType)
+			}
+
+			outs, err := collectOutputs(t, tr, c.in)
+			if err != nil {
+				if !c.transformErr {
+					t.Fatalf("transform shouldn't error out, got %v", err)
+				}
+				return
+			}
+			if c.transformErr {
+				t.Fatalf("transform should error out, but didn't")
+			}
+
+			if len(outs) != len(c.outs) || (len(outs) > 0 && !reflect.DeepEqual(outs, c.outs)) {
+				t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, c.outs)
+			}
+		})
+	}
+}
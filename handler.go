@@ -2,37 +2,51 @@ package transform
 
 import (
 	"context"
-	"log"
-	"os"
+	"log/slog"
+	"reflect"
 )
 
+// InputErrorHandler handles the error of transforming the given input
+// it should behave the same as the error handler of WithErrorHandler
+type InputErrorHandler func(ctx context.Context, input interface{}, err error) error
+
 // WithErrorHandler wraps the given transformer into a new one
 // if the original transformer returns an error, it is passed to the error handler
 // 	- if the handler returns nil, that's the same as if the transformer returned nil (it was handled)
 //	- if the handler returns an error back (same or different), transform will return an error
 func WithErrorHandler(t Transformer, errorHandler func(error) error) Transformer {
-	return errorHandlingTransformer{t, errorHandler}
+	return WithInputErrorHandler(t, func(_ context.Context, _ interface{}, err error) error {
+		return errorHandler(err)
+	})
+}
+
+// WithInputErrorHandler is the same as WithErrorHandler,
+// but the error handler also receives the context and the input which failed to transform
+// if t is a Streamer, transforming fails with ErrStreamer, and the error isn't handled
+func WithInputErrorHandler(t Transformer, errorHandler InputErrorHandler) Transformer {
+	return perValue(t, errorHandlingTransformer{t, errorHandler})
 }
 
 // LogErrors wraps the given transformer in a way that it logs all errors from it, but never fails
+// errors are logged to slog.Default(), use LogErrorsTo for more control
 func LogErrors(t Transformer) Transformer {
-	stdLog := log.New(os.Stderr, "transform: ", log.LstdFlags)
-	errorHandler := func(err error) error {
-		stdLog.Println(err)
-		return nil
-	}
-	return WithErrorHandler(t, errorHandler)
+	return LogErrorsTo(t, slog.Default(), LogOptions{})
 }
 
 type errorHandlingTransformer struct {
 	Transformer
-	errorHandler func(error) error
+	errorHandler InputErrorHandler
+}
+
+// OutputType is a part of the OutputTyper interface
+func (t errorHandlingTransformer) OutputType() reflect.Type {
+	return OutputType(t.Transformer)
 }
 
 // Transform is a part of the Transformer interface
 func (t errorHandlingTransformer) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
-	if err := t.Transformer.Transform(ctx, v, ch); err != nil {
-		return t.errorHandler(err)
+	if err := callTransform(ctx, "", t.Transformer, v, ch); err != nil {
+		return t.errorHandler(ctx, v, err)
 	}
 	return nil
 }
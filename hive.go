@@ -0,0 +1,458 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hiveTimeLayout is how LazySimpleSerDe writes timestamps
+const hiveTimeLayout = "2006-01-02 15:04:05.999999999"
+
+// HiveOptions configure the text format of Hive's LazySimpleSerDe
+// zero values are Hive's defaults
+type HiveOptions struct {
+	// FieldDelim separates columns, \x01 if it's zero (field.delim)
+	FieldDelim byte
+	// CollectionDelim separates elements of arrays and structs, and entries of maps, \x02 if it's zero (collection.delim)
+	CollectionDelim byte
+	// MapKeyDelim separates keys of maps from their values, \x03 if it's zero (mapkey.delim)
+	MapKeyDelim byte
+	// Null is the text of NULL values, \N if it's empty (serialization.null.format)
+	Null string
+	// Escape escapes delimiters, newlines and itself in values, values aren't escaped if it's zero (escape.delim)
+	Escape byte
+	// Columns are names of columns in the order they're stored in, they're matched with GetStructFieldName of fields
+	// columns are mapped to fields by position if it's empty
+	Columns []string
+}
+
+// hiveCodec encodes structs to lines of Hive text format and decodes them back
+type hiveCodec struct {
+	typ  reflect.Type
+	opts HiveOptions
+	// seps are the delimiters of nesting levels, seps[0] separates columns
+	seps []byte
+	// columns[i] is the index of the field stored in the i-th column, -1 if there is no such field
+	columns []int
+}
+
+func newHiveCodec(typ reflect.Type, opts HiveOptions, encode bool) (*hiveCodec, error) {
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s isn't a struct", typ)
+	}
+	if opts.FieldDelim == 0 {
+		opts.FieldDelim = '\x01'
+	}
+	if opts.CollectionDelim == 0 {
+		opts.CollectionDelim = '\x02'
+	}
+	if opts.MapKeyDelim == 0 {
+		opts.MapKeyDelim = '\x03'
+	}
+	if opts.Null == "" {
+		opts.Null = `\N`
+	}
+	// deeper levels use the same delimiters as Hive does
+	c := &hiveCodec{typ: typ, opts: opts, seps: []byte{opts.FieldDelim, opts.CollectionDelim, opts.MapKeyDelim, 4, 5, 6, 7, 8}}
+
+	fields := exportedFields(typ)
+	for _, i := range fields {
+		sf := typ.Field(i)
+		if err := c.check(sf.Type, 1); err != nil {
+			return nil, fmt.Errorf("field %s: %v", sf.Name, err)
+		}
+	}
+
+	if len(opts.Columns) == 0 {
+		c.columns = fields
+		return c, nil
+	}
+	fieldIdx := map[string]int{}
+	for _, i := range fields {
+		fieldIdx[GetStructFieldName(typ.Field(i))] = i
+	}
+	for _, name := range opts.Columns {
+		idx, ok := fieldIdx[strings.ToLower(name)]
+		if !ok {
+			if encode {
+				return nil, fmt.Errorf("column %q doesn't match any field of %s", name, typ)
+			}
+			idx = -1
+		}
+		c.columns = append(c.columns, idx)
+	}
+	return c, nil
+}
+
+// exportedFields returns indexes of exported fields of the struct type
+func exportedFields(typ reflect.Type) []int {
+	var fields []int
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).PkgPath == "" {
+			fields = append(fields, i)
+		}
+	}
+	return fields
+}
+
+// check checks if values of the type can be encoded at the given nesting level
+func (c *hiveCodec) check(typ reflect.Type, level int) error {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == timeType || typ == bytesType {
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Slice:
+		if level >= len(c.seps) {
+			return fmt.Errorf("%s is nested too deeply", typ)
+		}
+		if typ.Kind() == reflect.Slice {
+			return c.check(typ.Elem(), level+1)
+		}
+		for _, i := range exportedFields(typ) {
+			if err := c.check(typ.Field(i).Type, level+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if level+1 >= len(c.seps) {
+			return fmt.Errorf("%s is nested too deeply", typ)
+		}
+		if err := c.check(typ.Key(), level+2); err != nil {
+			return err
+		}
+		return c.check(typ.Elem(), level+2)
+	}
+	if !isText(typ) {
+		return fmt.Errorf("%s can't be stored in Hive text format", typ)
+	}
+	return nil
+}
+
+// split splits s by the delimiter, ignoring escaped delimiters
+func (c *hiveCodec) split(s string, sep byte) []string {
+	if c.opts.Escape == 0 {
+		return strings.Split(s, string(sep))
+	}
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case c.opts.Escape:
+			i++ // skip the escaped char
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescape removes escape chars from s
+func (c *hiveCodec) unescape(s string) string {
+	if c.opts.Escape == 0 || strings.IndexByte(s, c.opts.Escape) < 0 {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == c.opts.Escape && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			default:
+				sb.WriteByte(s[i])
+			}
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// writeEscaped writes s into buf, escaping delimiters, newlines and the escape char if escaping is enabled
+func (c *hiveCodec) writeEscaped(buf *bytes.Buffer, s string) {
+	if c.opts.Escape == 0 {
+		buf.WriteString(s)
+		return
+	}
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b == '\n':
+			buf.WriteByte(c.opts.Escape)
+			b = 'n'
+		case b == '\r':
+			buf.WriteByte(c.opts.Escape)
+			b = 'r'
+		case b == c.opts.Escape || bytes.IndexByte(c.seps, b) >= 0:
+			buf.WriteByte(c.opts.Escape)
+		}
+		buf.WriteByte(b)
+	}
+}
+
+// decode decodes a line into a new struct
+// columns which don't exist in the line are left empty
+func (c *hiveCodec) decode(line string) (reflect.Value, error) {
+	out := reflect.New(c.typ).Elem()
+	for i, s := range c.split(line, c.seps[0]) {
+		if i >= len(c.columns) {
+			break
+		}
+		idx := c.columns[i]
+		if idx < 0 {
+			continue
+		}
+		if err := c.decodeValue(s, out.Field(idx), 1); err != nil {
+			return reflect.Value{}, fmt.Errorf("column %d (%s): %v", i, GetStructFieldName(c.typ.Field(idx)), err)
+		}
+	}
+	return out, nil
+}
+
+func (c *hiveCodec) decodeValue(s string, v reflect.Value, level int) error {
+	if s == c.opts.Null {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	typ := v.Type()
+	switch {
+	case typ.Kind() == reflect.Ptr:
+		elem := reflect.New(typ.Elem())
+		if err := c.decodeValue(s, elem.Elem(), level); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case typ == timeType:
+		return parseText(c.unescape(s), v)
+	case typ == bytesType:
+		b, err := base64.StdEncoding.DecodeString(c.unescape(s))
+		if err != nil {
+			return err
+		}
+		v.SetBytes(b)
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		fields := exportedFields(typ)
+		for j, part := range c.split(s, c.seps[level]) {
+			if j >= len(fields) {
+				break
+			}
+			if err := c.decodeValue(part, v.Field(fields[j]), level+1); err != nil {
+				return fmt.Errorf("%s: %v", typ.Field(fields[j]).Name, err)
+			}
+		}
+		return nil
+	case reflect.Slice:
+		var parts []string
+		if s != "" {
+			parts = c.split(s, c.seps[level])
+		}
+		slice := reflect.MakeSlice(typ, len(parts), len(parts))
+		for j, part := range parts {
+			if err := c.decodeValue(part, slice.Index(j), level+1); err != nil {
+				return fmt.Errorf("[%d]: %v", j, err)
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Map:
+		m := reflect.MakeMap(typ)
+		if s != "" {
+			for _, entry := range c.split(s, c.seps[level]) {
+				kv := append(c.split(entry, c.seps[level+1]), c.opts.Null)
+				key, value := reflect.New(typ.Key()).Elem(), reflect.New(typ.Elem()).Elem()
+				if err := c.decodeValue(kv[0], key, level+2); err != nil {
+					return fmt.Errorf("key %q: %v", kv[0], err)
+				}
+				if err := c.decodeValue(kv[1], value, level+2); err != nil {
+					return fmt.Errorf("[%q]: %v", kv[0], err)
+				}
+				m.SetMapIndex(key, value)
+			}
+		}
+		v.Set(m)
+		return nil
+	}
+	return parseText(c.unescape(s), v)
+}
+
+// encode encodes the struct into a line, without a newline
+func (c *hiveCodec) encode(v reflect.Value) (string, error) {
+	var buf bytes.Buffer
+	for i, idx := range c.columns {
+		if i > 0 {
+			buf.WriteByte(c.seps[0])
+		}
+		if err := c.encodeValue(&buf, v.Field(idx), 1); err != nil {
+			return "", fmt.Errorf("column %d (%s): %v", i, GetStructFieldName(c.typ.Field(idx)), err)
+		}
+	}
+	return buf.String(), nil
+}
+
+func (c *hiveCodec) encodeValue(buf *bytes.Buffer, v reflect.Value, level int) error {
+	typ := v.Type()
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			buf.WriteString(c.opts.Null)
+			return nil
+		}
+	}
+
+	switch {
+	case typ.Kind() == reflect.Ptr:
+		return c.encodeValue(buf, v.Elem(), level)
+	case typ == timeType:
+		c.writeEscaped(buf, v.Interface().(time.Time).Format(hiveTimeLayout))
+		return nil
+	case typ == bytesType:
+		buf.WriteString(base64.StdEncoding.EncodeToString(v.Bytes()))
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		for j, idx := range exportedFields(typ) {
+			if j > 0 {
+				buf.WriteByte(c.seps[level])
+			}
+			if err := c.encodeValue(buf, v.Field(idx), level+1); err != nil {
+				return fmt.Errorf("%s: %v", typ.Field(idx).Name, err)
+			}
+		}
+		return nil
+	case reflect.Slice:
+		for j := 0; j < v.Len(); j++ {
+			if j > 0 {
+				buf.WriteByte(c.seps[level])
+			}
+			if err := c.encodeValue(buf, v.Index(j), level+1); err != nil {
+				return fmt.Errorf("[%d]: %v", j, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		// keys are sorted by their text, so that lines are deterministic
+		keys := make([]string, 0, v.Len())
+		entries := map[string]reflect.Value{}
+		for _, k := range v.MapKeys() {
+			var kb bytes.Buffer
+			if err := c.encodeValue(&kb, k, level+2); err != nil {
+				return fmt.Errorf("key %v: %v", k, err)
+			}
+			keys = append(keys, kb.String())
+			entries[kb.String()] = v.MapIndex(k)
+		}
+		sort.Strings(keys)
+		for j, k := range keys {
+			if j > 0 {
+				buf.WriteByte(c.seps[level])
+			}
+			buf.WriteString(k)
+			buf.WriteByte(c.seps[level+1])
+			if err := c.encodeValue(buf, entries[k], level+2); err != nil {
+				return fmt.Errorf("[%s]: %v", k, err)
+			}
+		}
+		return nil
+	}
+
+	s, err := formatText(v)
+	if err != nil {
+		return err
+	}
+	c.writeEscaped(buf, s)
+	return nil
+}
+
+// NewHiveDecoder creates a transformer which decodes lines of Hive text format (strings, e.g. from LineSource)
+// into values of the given struct type
+// columns are mapped to fields by position, or by opts.Columns, columns without a matching field are ignored
+// structs are stored as Hive STRUCTs, slices as ARRAYs and maps as MAPs, []byte as base64 BINARY
+// NULLs are decoded as zero values, so use pointers for nullable columns
+// lines which can't be decoded fail with an error, so they can be handled with WithErrorHandler or WithDeadLetter
+func NewHiveDecoder(outputType reflect.Type, opts HiveOptions) (Transformer, error) {
+	c, err := newHiveCodec(outputType, opts, false)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode Hive text into %s: %v", outputType, err)
+	}
+	return hiveDecoder{c}, nil
+}
+
+type hiveDecoder struct {
+	*hiveCodec
+}
+
+// InputType is part of the Transformer interface
+func (d hiveDecoder) InputType() reflect.Type {
+	return stringType
+}
+
+// OutputType is part of the OutputTyper interface
+func (d hiveDecoder) OutputType() reflect.Type {
+	return d.typ
+}
+
+// Transform is part of the Transformer interface
+func (d hiveDecoder) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	out, err := d.decode(v.(string))
+	if err != nil {
+		return err
+	}
+	return send(ctx, ch, out.Interface())
+}
+
+// NewHiveEncoder creates a transformer which encodes values of the given struct type into lines of Hive text format
+// (strings, e.g. for LineSink), the same way NewHiveDecoder decodes them
+// all exported fields are written in order, or only the ones named by opts.Columns in that order
+// nil pointers, slices and maps are written as NULL
+func NewHiveEncoder(inputType reflect.Type, opts HiveOptions) (Transformer, error) {
+	c, err := newHiveCodec(inputType, opts, true)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode %s as Hive text: %v", inputType, err)
+	}
+	return hiveEncoder{c}, nil
+}
+
+type hiveEncoder struct {
+	*hiveCodec
+}
+
+// InputType is part of the Transformer interface
+func (e hiveEncoder) InputType() reflect.Type {
+	return e.typ
+}
+
+// OutputType is part of the OutputTyper interface
+func (e hiveEncoder) OutputType() reflect.Type {
+	return stringType
+}
+
+// Transform is part of the Transformer interface
+func (e hiveEncoder) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	line, err := e.encode(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	return send(ctx, ch, line)
+}
@@ -0,0 +1,172 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHive(t *testing.T) {
+	type location struct {
+		Lat, Lng float64
+	}
+	type row struct {
+		ID      int `hive:"user_id"`
+		Name    *string
+		Tags    []string
+		Attrs   map[string]int
+		Loc     location
+		Visits  []location
+		Created time.Time
+		Raw     []byte
+	}
+
+	name := "a,b\nc\\"
+	created := time.Date(2020, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	full := row{
+		ID:      1,
+		Name:    &name,
+		Tags:    []string{"x", "y"},
+		Attrs:   map[string]int{"b": 2, "a": 1},
+		Loc:     location{1.5, -2},
+		Visits:  []location{{1, 2}, {3, 4}},
+		Created: created,
+		Raw:     []byte("hi"),
+	}
+
+	cases := []struct {
+		opts HiveOptions
+		line string
+		row  row
+		// line is encoded from the row as is if encoded is empty
+		encoded string
+		err     bool
+	}{
+		{
+			line: "1\x01a,b\nc\\\x01x\x02y\x01a\x031\x02b\x032\x011.5\x02-2\x011\x032\x023\x034\x012020-01-02 03:04:05.6\x01aGk=",
+			row:  full,
+		},
+		{
+			line: "2\x01\\N\x01\\N\x01\\N\x010\x020\x01\\N\x010001-01-01 00:00:00\x01\\N",
+			row:  row{ID: 2},
+		},
+		{
+			line:    "3\x01\x01\x01\x010\x020\x01\x012020-01-02\x01",
+			row:     row{ID: 3, Name: new(string), Tags: []string{}, Attrs: map[string]int{}, Visits: []location{}, Created: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), Raw: []byte{}},
+			encoded: "3\x01\x01\x01\x010\x020\x01\x012020-01-02 00:00:00\x01",
+		},
+		{
+			opts: HiveOptions{FieldDelim: ',', Escape: '\\', Null: "NULL"},
+			line: "1,a\\,b\\nc\\\\,x\x02y,a\x031\x02b\x032,1.5\x02-2,1\x032\x023\x034,2020-01-02 03:04:05.6,aGk=",
+			row:  full,
+		},
+		{
+			opts:    HiveOptions{Columns: []string{"name", "unknown", "USER_ID"}},
+			line:    "x\x01y\x017",
+			row:     row{ID: 7, Name: &[]string{"x"}[0]},
+			encoded: "-", // can't encode unknown columns
+		},
+		{
+			line:    "4",
+			row:     row{ID: 4},
+			encoded: "4\x01\\N\x01\\N\x01\\N\x010\x020\x01\\N\x010001-01-01 00:00:00\x01\\N",
+		},
+		{
+			line: "one\x01x",
+			err:  true,
+		},
+		{
+			line: "5\x01\x01\x01a\x03one",
+			err:  true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			decoder, err := NewHiveDecoder(reflect.TypeOf(row{}), c.opts)
+			if err != nil {
+				t.Fatalf("can't create decoder: %v", err)
+			}
+			outs, err := collectOutputs(t, decoder, c.line)
+			if err != nil {
+				if !c.err {
+					t.Fatalf("can't decode: %v", err)
+				}
+				if !strings.Contains(err.Error(), "column 0 (user_id)") && !strings.Contains(err.Error(), "column 3 (attrs)") {
+					t.Fatalf("error doesn't contain the column: %v", err)
+				}
+				return
+			}
+			if c.err {
+				t.Fatalf("shouldn't be able to decode %q", c.line)
+			}
+			if len(outs) != 1 || !reflect.DeepEqual(outs[0], c.row) {
+				t.Fatalf("decoded row mismatch\n\thave:\t%+v\n\twant:\t%+v", outs, c.row)
+			}
+
+			encoder, err := NewHiveEncoder(reflect.TypeOf(row{}), c.opts)
+			if c.encoded == "-" {
+				if err == nil {
+					t.Fatalf("shouldn't be able to create encoder")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("can't create encoder: %v", err)
+			}
+			outs, err = collectOutputs(t, encoder, c.row)
+			if err != nil {
+				t.Fatalf("can't encode: %v", err)
+			}
+			encoded := c.encoded
+			if encoded == "" {
+				encoded = c.line
+			}
+			if len(outs) != 1 || outs[0] != encoded {
+				t.Fatalf("encoded line mismatch\n\thave:\t%q\n\twant:\t%q", outs, encoded)
+			}
+		})
+	}
+}
+
+func TestHiveLines(t *testing.T) {
+	type foo struct {
+		A int
+		B []string
+	}
+
+	decoder, err := NewHiveDecoder(reflect.TypeOf(foo{}), HiveOptions{})
+	if err != nil {
+		t.Fatalf("can't create decoder: %v", err)
+	}
+	encoder, err := NewHiveEncoder(reflect.TypeOf(foo{}), HiveOptions{})
+	if err != nil {
+		t.Fatalf("can't create encoder: %v", err)
+	}
+	tr, err := Chain(decoder, encoder)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	input := "1\x01a\x02b\n2\x01\\N\n"
+	var buf bytes.Buffer
+	if err := Run(context.Background(), LineSource(strings.NewReader(input)), tr, LineSink(&buf)); err != nil {
+		t.Fatalf("can't run: %v", err)
+	}
+	if buf.String() != input {
+		t.Fatalf("output mismatch\n\thave:\t%q\n\twant:\t%q", buf.String(), input)
+	}
+
+	if err := LineSink(&buf).Write(context.Background(), channelOf(1)); err == nil {
+		t.Fatalf("shouldn't be able to write ints as lines")
+	}
+	for _, typ := range []reflect.Type{reflect.TypeOf(1), reflect.TypeOf(struct{ C chan int }{}), reflect.TypeOf(struct{ X [][][][][][][][]int }{})} {
+		if _, err := NewHiveDecoder(typ, HiveOptions{}); err == nil {
+			t.Fatalf("shouldn't be able to decode %s", typ)
+		}
+	}
+}
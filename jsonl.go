@@ -0,0 +1,301 @@
+package transform
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// JSONLinesSource creates a source which decodes every line of r into a new value of type typ,
+// which is usually the InputType of the transformer values are read for
+// struct fields, including fields of nested structs, are decoded from keys named by GetStructFieldName,
+// so it works with anonymous types built by NewStructExpander and NewStructCollapser, which don't have json tags
+// keys which don't match any field are ignored, and empty lines are skipped
+func JSONLinesSource(r io.Reader, typ reflect.Type) Source {
+	return jsonLinesSource{r, typ}
+}
+
+type jsonLinesSource struct {
+	r   io.Reader
+	typ reflect.Type
+}
+
+// Read is part of the Source interface
+func (s jsonLinesSource) Read(ctx context.Context, ch chan<- interface{}) error {
+	scanner := bufio.NewScanner(s.r)
+	scanner.Buffer(nil, 1<<24)
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		v := reflect.New(s.typ).Elem()
+		if err := decodeJSON(b, v); err != nil {
+			return fmt.Errorf("line %d: can't decode %s: %v", line, s.typ, err)
+		}
+		if err := send(ctx, ch, v.Interface()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// NewJSONDecoder creates a transformer which decodes strings with JSON into values of the given type,
+// the same way JSONLinesSource decodes lines, e.g. to decode lines of LineSource in a pipeline
+// empty strings are skipped, and strings which can't be decoded fail with an error,
+// so they can be handled with WithErrorHandler or WithDeadLetter
+func NewJSONDecoder(outputType reflect.Type) Transformer {
+	return jsonDecoder{outputType}
+}
+
+type jsonDecoder struct {
+	outputType reflect.Type
+}
+
+// InputType is part of the Transformer interface
+func (d jsonDecoder) InputType() reflect.Type {
+	return stringType
+}
+
+// OutputType is part of the OutputTyper interface
+func (d jsonDecoder) OutputType() reflect.Type {
+	return d.outputType
+}
+
+// Transform is part of the Transformer interface
+func (d jsonDecoder) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	b := bytes.TrimSpace([]byte(v.(string)))
+	if len(b) == 0 {
+		return nil
+	}
+	out := reflect.New(d.outputType).Elem()
+	if err := decodeJSON(b, out); err != nil {
+		return fmt.Errorf("can't decode %s: %v", d.outputType, err)
+	}
+	return send(ctx, ch, out.Interface())
+}
+
+// JSONLinesSink creates a sink which encodes every value as a line of JSON
+// struct fields are encoded the same way JSONLinesSource decodes them, in the order of fields
+func JSONLinesSink(w io.Writer) Sink {
+	return jsonLinesSink{w}
+}
+
+type jsonLinesSink struct {
+	w io.Writer
+}
+
+// Write is part of the Sink interface
+func (s jsonLinesSink) Write(ctx context.Context, ch <-chan interface{}) error {
+	bw := bufio.NewWriter(s.w)
+	var buf bytes.Buffer
+	for {
+		v, more, err := recv(ctx, ch)
+		if err != nil || !more {
+			if err == nil {
+				err = bw.Flush()
+			}
+			return err
+		}
+
+		buf.Reset()
+		if err := encodeJSON(&buf, reflect.ValueOf(v)); err != nil {
+			return fmt.Errorf("can't encode %T: %v", v, err)
+		}
+		buf.WriteByte('\n')
+		if _, err := bw.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeJSON decodes b into v, with struct fields named by GetStructFieldName
+func decodeJSON(b []byte, v reflect.Value) error {
+	typ := v.Type()
+	if reflect.PtrTo(typ).Implements(jsonUnmarshalerType) {
+		return json.Unmarshal(b, v.Addr().Interface())
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		if string(b) == "null" {
+			v.Set(reflect.Zero(typ))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(typ.Elem()))
+		}
+		return decodeJSON(b, v.Elem())
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return err
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			if sf.PkgPath != "" {
+				continue // not exported
+			}
+			name := GetStructFieldName(sf)
+			if raw, ok := fields[name]; ok {
+				if err := decodeJSON(raw, v.Field(i)); err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+			}
+		}
+		return nil
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			break // []byte is base64 encoded
+		}
+		var elems []json.RawMessage
+		if err := json.Unmarshal(b, &elems); err != nil {
+			return err
+		}
+		if elems == nil {
+			v.Set(reflect.Zero(typ))
+			return nil
+		}
+		s := reflect.MakeSlice(typ, len(elems), len(elems))
+		for i, elem := range elems {
+			if err := decodeJSON(elem, s.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %v", i, err)
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			break
+		}
+		var elems map[string]json.RawMessage
+		if err := json.Unmarshal(b, &elems); err != nil {
+			return err
+		}
+		if elems == nil {
+			v.Set(reflect.Zero(typ))
+			return nil
+		}
+		m := reflect.MakeMapWithSize(typ, len(elems))
+		for k, elem := range elems {
+			ev := reflect.New(typ.Elem()).Elem()
+			if err := decodeJSON(elem, ev); err != nil {
+				return fmt.Errorf("%s: %v", k, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(typ.Key()), ev)
+		}
+		v.Set(m)
+		return nil
+	}
+	return json.Unmarshal(b, v.Addr().Interface())
+}
+
+// encodeJSON encodes v into buf, with struct fields named by GetStructFieldName
+func encodeJSON(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	typ := v.Type()
+	if typ.Implements(jsonMarshalerType) {
+		return marshalJSON(buf, v.Interface())
+	}
+	if v.CanAddr() && reflect.PtrTo(typ).Implements(jsonMarshalerType) {
+		return marshalJSON(buf, v.Addr().Interface())
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encodeJSON(buf, v.Elem())
+	case reflect.Struct:
+		buf.WriteByte('{')
+		first := true
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			if sf.PkgPath != "" {
+				continue // not exported
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			if err := marshalJSON(buf, GetStructFieldName(sf)); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeJSON(buf, v.Field(i)); err != nil {
+				return fmt.Errorf("%s: %v", GetStructFieldName(sf), err)
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			break // []byte is base64 encoded
+		}
+		if typ.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, v.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %v", i, err)
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := marshalJSON(buf, k.String()); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeJSON(buf, v.MapIndex(k)); err != nil {
+				return fmt.Errorf("%s: %v", k.String(), err)
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+	return marshalJSON(buf, v.Interface())
+}
+
+func marshalJSON(buf *bytes.Buffer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
@@ -0,0 +1,141 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLines(t *testing.T) {
+	type item struct {
+		SKU   string `hive:"sku_id"`
+		Price *float64
+	}
+	type order struct {
+		ID      int `hive:"order_id"`
+		Created time.Time
+		Items   []item
+		Extra   map[string]item
+		Note    *string
+		hidden  int
+	}
+
+	price := 2.5
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	collapser, err := NewStructCollapser(reflect.TypeOf(order{}), []string{"items", "order_id"})
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+
+	cases := []struct {
+		typ   reflect.Type
+		lines string
+		// values are encoded back into lines, which should be the same as the lines, if they're set
+		values []interface{}
+		output string
+		err    bool
+	}{
+		{
+			typ:   reflect.TypeOf(order{}),
+			lines: `{"order_id":1,"created":"2020-01-02T03:04:05Z","items":[{"sku_id":"a","price":2.5},{"sku_id":"b","price":null}],"extra":{"x":{"sku_id":"c","price":null}},"note":null}` + "\n",
+			values: []interface{}{order{
+				ID:      1,
+				Created: created,
+				Items:   []item{{"a", &price}, {"b", nil}},
+				Extra:   map[string]item{"x": {"c", nil}},
+			}},
+		},
+		{
+			typ:    reflect.TypeOf(order{}),
+			lines:  "{\"ORDER_ID\": 2, \"unknown\": true}\n\n  \n{}\n",
+			values: []interface{}{order{}, order{}},
+			output: `{"order_id":0,"created":"0001-01-01T00:00:00Z","items":null,"extra":null,"note":null}` + "\n" +
+				`{"order_id":0,"created":"0001-01-01T00:00:00Z","items":null,"extra":null,"note":null}` + "\n",
+		},
+		{
+			typ:   OutputType(collapser),
+			lines: `{"items":[{"sku_id":"a","price":null}],"order_id":3}` + "\n",
+			values: []interface{}{reflect.ValueOf(struct {
+				Items []item
+				ID    int `hive:"order_id"`
+			}{[]item{{"a", nil}}, 3}).Convert(OutputType(collapser)).Interface()},
+		},
+		{
+			typ:    reflect.TypeOf(1),
+			lines:  "1\n2\n",
+			values: []interface{}{1, 2},
+		},
+		{
+			typ:   reflect.TypeOf(order{}),
+			lines: "{}\n{\"items\": [{\"price\": \"free\"}]}\n",
+			err:   true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			ch := make(chan interface{}, 10)
+			err := JSONLinesSource(strings.NewReader(c.lines), c.typ).Read(context.Background(), ch)
+			close(ch)
+			if err != nil {
+				if !c.err {
+					t.Fatalf("can't read values: %v", err)
+				}
+				if !strings.Contains(err.Error(), "line 2") {
+					t.Fatalf("error doesn't contain the line: %v", err)
+				}
+				return
+			}
+			if c.err {
+				t.Fatalf("shouldn't be able to read values")
+			}
+
+			var values []interface{}
+			for v := range ch {
+				values = append(values, v)
+			}
+			if !reflect.DeepEqual(values, c.values) {
+				t.Fatalf("values mismatch\n\thave:\t%+v\n\twant:\t%+v", values, c.values)
+			}
+
+			ch = make(chan interface{}, len(values))
+			for _, v := range values {
+				ch <- v
+			}
+			close(ch)
+			var buf bytes.Buffer
+			if err := JSONLinesSink(&buf).Write(context.Background(), ch); err != nil {
+				t.Fatalf("can't write values: %v", err)
+			}
+			output := c.output
+			if output == "" {
+				output = c.lines
+			}
+			if buf.String() != output {
+				t.Fatalf("output mismatch\n\thave:\t%s\n\twant:\t%s", buf.String(), output)
+			}
+		})
+	}
+}
+
+func TestJSONDecoder(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	decoder := NewJSONDecoder(reflect.TypeOf(point{}))
+	if OutputType(decoder) != reflect.TypeOf(point{}) {
+		t.Fatalf("unexpected output type %s", OutputType(decoder))
+	}
+
+	outs := streamOutputs(t, decoder, `{"x": 1, "y": 2}`, "  ", `{"y": 3}`)
+	if want := []interface{}{point{1, 2}, point{0, 3}}; !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+	if _, err := collectOutputs(t, decoder, `{"x": "one"}`); err == nil {
+		t.Fatalf("shouldn't be able to decode a string into an int")
+	}
+}
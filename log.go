@@ -0,0 +1,152 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogOptions configure how LogErrorsTo logs errors
+type LogOptions struct {
+	// Stage is logged as the name of the stage which failed
+	// if it's empty, the path from the TransformError is used, or the type of the transformer if there is no path
+	Stage string
+	// Level returns the level an error is logged at, if it's nil all errors are logged at slog.LevelError
+	Level func(error) slog.Level
+	// MaxPreview is the maximum length of the logged input value preview, 0 means 256
+	// the preview is made from the input returned by RedactInput
+	MaxPreview int
+	// Sampling limits how many errors are logged, if it's nil all errors are logged
+	Sampling *LogSampling
+}
+
+// LogSampling limits logs of noisy failures
+// errors are grouped by the stage and the type of the cause, and in every Tick only
+// the First errors of each group are logged, and after that every Thereafter-th one
+// if Tick is 0, counting never starts over
+type LogSampling struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+// LogErrorsTo wraps the given transformer in a way that it logs all errors from it to the logger, but never fails
+// every error is logged as a record with the following attributes:
+//   - stage: name of the stage which failed
+//   - input_type: type of the input value
+//   - input: preview of the input value
+//   - attempts: number of attempts if the transformer was wrapped with WithRetry, 1 otherwise
+//   - error: the cause of the error, without the input and the path
+func LogErrorsTo(t Transformer, logger *slog.Logger, opts LogOptions) Transformer {
+	sampler := newLogSampler(opts.Sampling)
+	errorHandler := func(ctx context.Context, v interface{}, err error) error {
+		path, input, cause := splitTransformError(newTransformError(v, err))
+		stage := opts.Stage
+		if stage == "" {
+			stage = strings.Join(path, "/")
+		}
+		if stage == "" {
+			stage = nameOf(t)
+		}
+		if !sampler.sample(stage, rootCause(cause)) {
+			return nil
+		}
+
+		level := slog.LevelError
+		if opts.Level != nil {
+			level = opts.Level(err)
+		}
+		attempts := 1
+		var retryErr *RetryError
+		if errors.As(err, &retryErr) {
+			attempts = retryErr.Attempts
+		}
+
+		logger.LogAttrs(ctx, level, "transform failed",
+			slog.String("stage", stage),
+			slog.String("input_type", fmt.Sprintf("%T", v)),
+			slog.String("input", preview(input, opts.MaxPreview)),
+			slog.Int("attempts", attempts),
+			slog.String("error", cause.Error()),
+		)
+		return nil
+	}
+	return WithInputErrorHandler(t, errorHandler)
+}
+
+// splitTransformError walks through all TransformErrors wrapped in err (e.g. by WithRetry)
+// returns their joined paths, and the input and the cause of the innermost one
+func splitTransformError(err error) (path []string, input interface{}, cause error) {
+	cause = err
+	for err != nil {
+		if te, ok := err.(*TransformError); ok {
+			path = append(path, te.Path...)
+			input, cause = te.Input, te.Err
+		}
+		err = errors.Unwrap(err)
+	}
+	return path, input, cause
+}
+
+// preview formats the value and truncates it to max characters
+func preview(v interface{}, max int) string {
+	if max <= 0 {
+		max = 256
+	}
+	s := fmt.Sprintf("%+v", v)
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
+
+// rootCause returns the innermost error
+func rootCause(err error) error {
+	for {
+		unwrapped := errors.Unwrap(err)
+		if unwrapped == nil {
+			return err
+		}
+		err = unwrapped
+	}
+}
+
+type logSampler struct {
+	sampling *LogSampling
+
+	mu     sync.Mutex
+	counts map[[2]string]int
+	reset  time.Time
+}
+
+func newLogSampler(sampling *LogSampling) *logSampler {
+	return &logSampler{sampling: sampling, counts: map[[2]string]int{}}
+}
+
+// sample checks if an error of the stage with the given cause should be logged
+// causes are compared by type, messages often contain the input which would make every error unique
+func (s *logSampler) sample(stage string, cause error) bool {
+	if s.sampling == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); s.sampling.Tick > 0 && now.After(s.reset) {
+		s.counts = map[[2]string]int{}
+		s.reset = now.Add(s.sampling.Tick)
+	}
+
+	key := [2]string{stage, fmt.Sprintf("%T", cause)}
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= s.sampling.First {
+		return true
+	}
+	return s.sampling.Thereafter > 0 && (n-s.sampling.First)%s.sampling.Thereafter == 0
+}
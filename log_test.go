@@ -0,0 +1,118 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestLogErrorsTo(t *testing.T) {
+	alwaysFails, _ := FromFunction(func(x int) (int, error) { return 0, errors.New("always fails") })
+	fail := WithRetry(alwaysFails, RetryPolicy{MaxAttempts: 2})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	tr := LogErrorsTo(fail, logger, LogOptions{
+		Stage: "fail",
+		Level: func(err error) slog.Level {
+			if errors.Is(err, errors.ErrUnsupported) {
+				return slog.LevelError
+			}
+			return slog.LevelWarn
+		},
+		Sampling: &LogSampling{First: 2, Thereafter: 3},
+	})
+
+	inputs := make([]interface{}, 10)
+	for i := range inputs {
+		inputs[i] = 1234567
+	}
+	if outs := streamOutputs(t, tr, inputs...); len(outs) != 0 {
+		t.Fatalf("transform shouldn't output anything, got %v", outs)
+	}
+
+	var records []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("can't decode log record: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	// errors 1, 2, 5 and 8 are logged
+	if len(records) != 4 {
+		t.Fatalf("unexpected number of log records\n\thave: %d\n\twant: %d", len(records), 4)
+	}
+	want := map[string]interface{}{
+		"level":      "WARN",
+		"msg":        "transform failed",
+		"stage":      "fail",
+		"input_type": "int",
+		"input":      "1234567",
+		"attempts":   2.0,
+	}
+	for _, record := range records {
+		for k, v := range want {
+			if !reflect.DeepEqual(record[k], v) {
+				t.Fatalf("log attribute %q mismatch:\n\thave: %v\n\twant: %v", k, record[k], v)
+			}
+		}
+		if record["error"] != "always fails" {
+			t.Fatalf("unexpected error in log record: %v", record["error"])
+		}
+	}
+
+	if p := preview("abcdef", 3); p != "abc..." {
+		t.Fatalf("preview mismatch:\n\thave: %s\n\twant: abc...", p)
+	}
+}
+
+func TestLogErrorsToDefaults(t *testing.T) {
+	fail, _ := FromFunction(func(x int) (int, error) { return 0, fmt.Errorf("can't transform %d", x) })
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	tr := LogErrorsTo(fail, logger, LogOptions{Sampling: &LogSampling{First: 1}})
+	streamOutputs(t, tr, 1, 2, 3)
+
+	var records []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("can't decode log record: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	// errors differ only in the message, so they're sampled together
+	if len(records) != 1 {
+		t.Fatalf("unexpected number of log records\n\thave: %d\n\twant: %d", len(records), 1)
+	}
+	if stage := records[0]["stage"]; stage != "*transform.function" {
+		t.Fatalf("stage mismatch:\n\thave: %v\n\twant: *transform.function", stage)
+	}
+
+	// errors of other types or stages are sampled separately
+	sampler := newLogSampler(&LogSampling{First: 1})
+	for i, tc := range []struct {
+		stage string
+		err   error
+		want  bool
+	}{
+		{"a", errors.New("x"), true},
+		{"a", errors.New("y"), false},
+		{"a", &RetryError{Attempts: 1, Err: errors.New("x")}, true},
+		{"b", errors.New("x"), true},
+	} {
+		if have := sampler.sample(tc.stage, tc.err); have != tc.want {
+			t.Fatalf("case-%d: sample mismatch\n\thave: %v\n\twant: %v", i, have, tc.want)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	// upper bounds of latency histogram buckets, in seconds
+	latencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+	// upper bounds of fan-out (outputs per input) histogram buckets
+	fanOutBuckets = []float64{0, 1, 2, 4, 8, 16, 32, 64}
+)
+
+// MetricsRegistry collects metrics of all stages of instrumented transformers
+// it's an http.Handler which exports them in the Prometheus text exposition format
+type MetricsRegistry struct {
+	mu     sync.Mutex
+	stages map[string]*stageMetrics
+}
+
+// NewMetricsRegistry creates an empty registry
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{stages: map[string]*stageMetrics{}}
+}
+
+// StageStats are counters of a single stage
+type StageStats struct {
+	// Received is the number of values the stage received
+	Received int64
+	// Emitted is the number of values the stage sent to its output channel
+	Emitted int64
+	// Failed is the number of values (or streams, for streaming stages) the stage failed to transform
+	Failed int64
+	// InFlight is the number of values the stage is currently transforming
+	InFlight int64
+}
+
+type stageMetrics struct {
+	received, emitted, failed, inFlight int64
+	latency                             *histogram
+	fanOut                              *histogram
+}
+
+// stage returns metrics of the stage, creating them if needed
+func (r *MetricsRegistry) stage(name string) *stageMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.stages[name]
+	if !ok {
+		m = &stageMetrics{latency: newHistogram(latencyBuckets), fanOut: newHistogram(fanOutBuckets)}
+		r.stages[name] = m
+	}
+	return m
+}
+
+// Stats returns counters of the stage with the given name
+// names are stage paths joined with "/", the root transformer is named "root"
+func (r *MetricsRegistry) Stats(name string) (StageStats, bool) {
+	r.mu.Lock()
+	m, ok := r.stages[name]
+	r.mu.Unlock()
+	if !ok {
+		return StageStats{}, false
+	}
+	return StageStats{
+		Received: atomic.LoadInt64(&m.received),
+		Emitted:  atomic.LoadInt64(&m.emitted),
+		Failed:   atomic.LoadInt64(&m.failed),
+		InFlight: atomic.LoadInt64(&m.inFlight),
+	}, true
+}
+
+// ServeHTTP is part of the http.Handler interface
+func (r *MetricsRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// WriteTo writes all metrics to w in the Prometheus text exposition format
+func (r *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.stages))
+	for name := range r.stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// the map can't be read without the lock, metrics of a stage can
+	stages := make([]*stageMetrics, len(names))
+	for i, name := range names {
+		stages[i] = r.stages[name]
+	}
+	r.mu.Unlock()
+
+	var sb strings.Builder
+	counter := func(metric, help string, value func(*stageMetrics) *int64, typ string) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", metric, help, metric, typ)
+		for i, name := range names {
+			fmt.Fprintf(&sb, "%s{stage=%s} %d\n", metric, labelValue(name), atomic.LoadInt64(value(stages[i])))
+		}
+	}
+	counter("transform_received_total", "Number of values a stage received.", func(m *stageMetrics) *int64 { return &m.received }, "counter")
+	counter("transform_emitted_total", "Number of values a stage emitted.", func(m *stageMetrics) *int64 { return &m.emitted }, "counter")
+	counter("transform_failed_total", "Number of failed transforms of a stage.", func(m *stageMetrics) *int64 { return &m.failed }, "counter")
+	counter("transform_in_flight", "Number of values a stage is currently transforming.", func(m *stageMetrics) *int64 { return &m.inFlight }, "gauge")
+
+	histograms := func(metric, help string, h func(*stageMetrics) *histogram) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s histogram\n", metric, help, metric)
+		for i, name := range names {
+			h(stages[i]).write(&sb, metric, name)
+		}
+	}
+	histograms("transform_duration_seconds", "Duration of a single Transform call of a stage.", func(m *stageMetrics) *histogram { return m.latency })
+	histograms("transform_fan_out", "Number of values a stage emitted for a single input.", func(m *stageMetrics) *histogram { return m.fanOut })
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// escapes label values, the text format only has escapes for backslashes, double quotes and line feeds
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue returns s quoted as a label value in the Prometheus text format
+func labelValue(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	// counts[i] is the number of observations in (buckets[i-1], buckets[i]], last one is for +Inf
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[sort.SearchFloat64s(h.buckets, v)]++
+	h.sum += v
+	h.count++
+}
+
+// write writes the histogram in the Prometheus text format, buckets are cumulative
+func (h *histogram) write(sb *strings.Builder, metric, stage string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var cumulative uint64
+	for i, count := range h.counts {
+		cumulative += count
+		le := "+Inf"
+		if i < len(h.buckets) {
+			le = strconv.FormatFloat(h.buckets[i], 'g', -1, 64)
+		}
+		fmt.Fprintf(sb, "%s_bucket{stage=%s,le=%s} %d\n", metric, labelValue(stage), labelValue(le), cumulative)
+	}
+	fmt.Fprintf(sb, "%s_sum{stage=%s} %s\n", metric, labelValue(stage), strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(sb, "%s_count{stage=%s} %d\n", metric, labelValue(stage), h.count)
+}
+
+// Instrument wraps the given transformer, and all transformers it's built from (e.g. stages of a Chain),
+// in a way that their metrics are recorded in the registry
+// stages are named by their paths (the same as in TransformError) joined with "/", the root is named "root"
+// for streaming stages (see Streamer) only counters are recorded, since values aren't transformed one by one
+func Instrument(t Transformer, registry *MetricsRegistry) Transformer {
+	return rewrite(t, nil, func(path []string, t Transformer) Transformer {
+		name := "root"
+		if len(path) > 0 {
+			name = strings.Join(path, "/")
+		}
+		instrumented := instrumentedTransformer{t, registry.stage(name)}
+		if _, ok := t.(Streamer); ok {
+			return instrumentedStreamer{instrumented}
+		}
+		return instrumented
+	})
+}
+
+type instrumentedTransformer struct {
+	Transformer
+	metrics *stageMetrics
+}
+
+// OutputType is a part of the OutputTyper interface
+func (t instrumentedTransformer) OutputType() reflect.Type {
+	return OutputType(t.Transformer)
+}
+
+// Transform is a part of the Transformer interface
+func (t instrumentedTransformer) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	atomic.AddInt64(&t.metrics.received, 1)
+	atomic.AddInt64(&t.metrics.inFlight, 1)
+	defer atomic.AddInt64(&t.metrics.inFlight, -1)
+
+	// outputs are counted on their way to the channel
+	outCh := make(chan interface{})
+	emitted := make(chan int)
+	go func() {
+		n := 0
+		for out := range outCh {
+			if ctx.Err() != nil {
+				continue // drain, so the transformer doesn't block
+			}
+			select {
+			case ch <- out:
+				n++
+			case <-ctx.Done():
+			}
+		}
+		emitted <- n
+	}()
+
+	start := time.Now()
+	err := callTransform(ctx, "", t.Transformer, v, outCh)
+	close(outCh)
+	n := <-emitted
+
+	t.metrics.latency.observe(time.Since(start).Seconds())
+	t.metrics.fanOut.observe(float64(n))
+	atomic.AddInt64(&t.metrics.emitted, int64(n))
+	if err != nil {
+		atomic.AddInt64(&t.metrics.failed, 1)
+	}
+	return err
+}
+
+type instrumentedStreamer struct {
+	instrumentedTransformer
+}
+
+// TransformAll is a part of the Streamer interface
+func (t instrumentedStreamer) TransformAll(ctx context.Context, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	group, ctx := errgroup.WithContext(ctx)
+	in, out := make(chan interface{}), make(chan interface{})
+
+	group.Go(func() error {
+		defer close(in)
+		for {
+			select {
+			case v, more := <-inCh:
+				if !more {
+					return nil
+				}
+				atomic.AddInt64(&t.metrics.received, 1)
+				if err := send(ctx, in, v); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+	group.Go(func() error {
+		defer close(out)
+		err := All(ctx, t.Transformer, in, out)
+		if err != nil {
+			atomic.AddInt64(&t.metrics.failed, 1)
+		}
+		return err
+	})
+	group.Go(func() error {
+		for v := range out {
+			if err := send(ctx, outCh, v); err != nil {
+				return err
+			}
+			atomic.AddInt64(&t.metrics.emitted, 1)
+		}
+		return nil
+	})
+
+	return group.Wait()
+}
@@ -0,0 +1,107 @@
+package transform
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	inc, _ := FromFunction(func(x int) int { return x + 1 })
+	double, _ := FromFunction(func(x int) int { return 2 * x })
+	odd, _ := FromFunction(func(x int) bool { return x%2 == 1 })
+
+	par, err := InParallel(double, odd)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	tr, err := Chain(inc, par)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	registry := NewMetricsRegistry()
+	tr = Instrument(tr, registry)
+	if _, ok := tr.(Streamer); !ok {
+		t.Fatalf("instrumented chain should still be a streamer")
+	}
+	if OutputType(tr) != OutputType(par) {
+		t.Fatalf("instrumented transformer should keep the output type")
+	}
+
+	var inputs []interface{}
+	for i := 0; i < 10; i++ {
+		inputs = append(inputs, i)
+	}
+	outs := streamOutputs(t, tr, inputs...)
+	if len(outs) != 15 {
+		t.Fatalf("unexpected number of outputs\n\thave: %d\n\twant: %d", len(outs), 15)
+	}
+
+	for name, want := range map[string]StageStats{
+		"root":                 {Received: 10, Emitted: 15},
+		"chain[0]":             {Received: 10, Emitted: 10},
+		"chain[1]":             {Received: 10, Emitted: 15},
+		"chain[1]/parallel[0]": {Received: 10, Emitted: 10},
+		"chain[1]/parallel[1]": {Received: 10, Emitted: 5},
+	} {
+		have, ok := registry.Stats(name)
+		if !ok {
+			t.Fatalf("no metrics for stage %q", name)
+		}
+		if have != want {
+			t.Fatalf("stage %q stats mismatch:\n\thave: %+v\n\twant: %+v", name, have, want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, line := range []string{
+		"# TYPE transform_received_total counter",
+		`transform_emitted_total{stage="chain[1]/parallel[1]"} 5`,
+		`transform_duration_seconds_count{stage="chain[0]"} 10`,
+		`transform_fan_out_bucket{stage="chain[1]/parallel[1]",le="0"} 5`,
+		`transform_fan_out_bucket{stage="chain[1]/parallel[1]",le="+Inf"} 10`,
+		`transform_in_flight{stage="root"} 0`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Fatalf("exported metrics don't contain %q:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsRegistryConcurrentWrite(t *testing.T) {
+	registry := NewMetricsRegistry()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			registry.stage(fmt.Sprintf("stage-%d", i))
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if _, err := registry.WriteTo(io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	if _, ok := registry.Stats("stage-99"); !ok {
+		t.Fatal("expecting stage-99 to be registered")
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	for s, want := range map[string]string{
+		"chain[1]/parse":     `"chain[1]/parse"`,
+		"tab\tand \"quote\"": `"tab` + "\t" + `and \"quote\""`,
+		"back\\slash\nline":  `"back\\slash\nline"`,
+		"ünicode":            `"ünicode"`,
+	} {
+		if have := labelValue(s); have != want {
+			t.Fatalf("label value of %q mismatch\n\thave: %s\n\twant: %s", s, have, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Middleware wraps a transformer into a new one, e.g. Recover
+type Middleware func(Transformer) Transformer
+
+// Apply wraps every transformer in the tree of the given one with all middlewares
+// that includes transformers composites (Chain, InParallel, routers) are built from, and composites themselves
+// a transformer given a name with Named is the same stage as the named one, so only the named one is wrapped
+// middlewares are applied in the given order, so the first one is the innermost
+// returns an error if a middleware doesn't keep a Streamer (e.g. Chain or Batch) a Streamer, like Recover does,
+// since transforming its values one by one would change what it does, e.g. every Batch would have one value
+// use SkipStreamers to apply such middlewares (e.g. Timeout) only to transformers which aren't Streamers
+func Apply(root Transformer, mws ...Middleware) (Transformer, error) {
+	var err error
+	var apply func(path []string, t Transformer, wrap bool) Transformer
+	apply = func(path []string, t Transformer, wrap bool) Transformer {
+		if c, ok := t.(composite); ok {
+			_, named := asNamed(t)
+			children := c.children()
+			wrapped := make([]Transformer, len(children))
+			for i, child := range children {
+				wrapped[i] = apply(append(path[:len(path):len(path)], c.stage(i)), child, !named)
+			}
+			t = c.withChildren(wrapped)
+		}
+		if !wrap {
+			return t
+		}
+		for i, mw := range mws {
+			wrapped := mw(t)
+			if _, ok := t.(Streamer); ok && err == nil {
+				if _, ok := wrapped.(Streamer); !ok {
+					stage := "root"
+					if len(path) > 0 {
+						stage = strings.Join(path, "/")
+					}
+					err = fmt.Errorf("middleware %d doesn't keep %s (%s) a Streamer, use SkipStreamers to apply it", i, stage, nameOf(t))
+				}
+			}
+			t = wrapped
+		}
+		return t
+	}
+
+	t := apply(nil, root, true)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SkipStreamers returns a middleware which wraps transformers with mw, but leaves Streamers as they are
+// use it with Apply for middlewares which transform values one by one, transformers Streamers are built from are still wrapped
+func SkipStreamers(mw Middleware) Middleware {
+	return func(t Transformer) Transformer {
+		if _, ok := t.(Streamer); ok {
+			return t
+		}
+		return mw(t)
+	}
+}
+
+// Timeout returns a middleware which wraps transformers with WithTimeout
+func Timeout(d time.Duration) Middleware {
+	return func(t Transformer) Transformer {
+		return WithTimeout(t, d)
+	}
+}
+
+// WithTimeout wraps the given transformer in a way that every Transform call is cancelled after d
+// if t is a Streamer, transforming fails with ErrStreamer
+func WithTimeout(t Transformer, d time.Duration) Transformer {
+	return perValue(t, timeoutTransformer{t, d})
+}
+
+type timeoutTransformer struct {
+	Transformer
+	timeout time.Duration
+}
+
+// OutputType is a part of the OutputTyper interface
+func (t timeoutTransformer) OutputType() reflect.Type {
+	return OutputType(t.Transformer)
+}
+
+// Transform is a part of the Transformer interface
+func (t timeoutTransformer) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return callTransform(ctx, "", t.Transformer, v, ch)
+}
+
+// perValue returns wrapped, a wrapper of t which transforms values one by one
+// if t is a Streamer, a transformer which fails with ErrStreamer is returned instead,
+// since transforming values of t one by one would change what it does, e.g. every Batch would have one value
+func perValue(t, wrapped Transformer) Transformer {
+	if _, ok := t.(Streamer); ok {
+		return streamerMisuse{t}
+	}
+	return wrapped
+}
+
+type streamerMisuse struct {
+	Transformer
+}
+
+// OutputType is a part of the OutputTyper interface
+func (t streamerMisuse) OutputType() reflect.Type {
+	return OutputType(t.Transformer)
+}
+
+// Transform is a part of the Transformer interface
+func (t streamerMisuse) Transform(context.Context, interface{}, chan<- interface{}) error {
+	return fmt.Errorf("%w: %s", ErrStreamer, nameOf(t.Transformer))
+}
@@ -0,0 +1,136 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyStreamers(t *testing.T) {
+	inc, _ := FromFunction(func(x int) int { return x + 1 })
+	batch, err := Batch(reflect.TypeOf(1), 3, 0)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	tr, err := Chain(inc, batch)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	if _, err := Apply(tr, Recover, Timeout(time.Second)); err == nil {
+		t.Fatalf("Timeout shouldn't be applied to streamers")
+	}
+
+	// Recover keeps the chain and the batch streaming, and Timeout is only applied to inc
+	tr, err = Apply(tr, Recover, SkipStreamers(Timeout(time.Second)))
+	if err != nil {
+		t.Fatalf("can't apply middlewares: %v", err)
+	}
+	if _, ok := tr.(Streamer); !ok {
+		t.Fatalf("chain should stay a Streamer, got %T", tr)
+	}
+	outs := streamOutputs(t, tr, 1, 2, 3, 4, 5, 6)
+	if want := []interface{}{[]int{2, 3, 4}, []int{5, 6, 7}}; !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	inc, _ := FromFunction(func(x int) int { return x + 1 })
+	double, _ := FromFunction(func(x int) int { return 2 * x })
+	square, _ := FromFunction(func(x int) int { return x * x })
+
+	par, err := InParallel(double, square)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	tr, err := Chain(inc, par)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	var wrapped []string
+	record := func(name string) Middleware {
+		return func(t Transformer) Transformer {
+			wrapped = append(wrapped, name)
+			return Recover(t)
+		}
+	}
+
+	tr, err = Apply(tr, record("inner"), record("outer"))
+	if err != nil {
+		t.Fatalf("can't apply middlewares: %v", err)
+	}
+	// inc, double, square, parallel and chain are wrapped with both middlewares
+	if len(wrapped) != 10 {
+		t.Fatalf("unexpected number of wrapped transformers\n\thave: %d\n\twant: %d", len(wrapped), 10)
+	}
+	for i := 0; i < len(wrapped); i += 2 {
+		if wrapped[i] != "inner" || wrapped[i+1] != "outer" {
+			t.Fatalf("middlewares applied in wrong order: %v", wrapped)
+		}
+	}
+	if OutputType(tr) != reflect.TypeOf(1) {
+		t.Fatalf("wrapped transformer should keep the output type")
+	}
+
+	outs, err := collectOutputs(t, tr, 2)
+	if err != nil {
+		t.Fatalf("transform shouldn't error out, got %v", err)
+	}
+	var ints []int
+	for _, out := range outs {
+		ints = append(ints, out.(int))
+	}
+	sort.Ints(ints)
+	if want := []int{6, 9}; !reflect.DeepEqual(ints, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", ints, want)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	slow, _ := FromFunction(func(ctx context.Context, x int) (int, error) {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(time.Second):
+			return x, nil
+		}
+	})
+
+	tr, err := Apply(slow, Timeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("can't apply middlewares: %v", err)
+	}
+	if _, err := collectOutputs(t, tr, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expecting deadline exceeded, got %v", err)
+	}
+}
+
+func TestApplyNamed(t *testing.T) {
+	inc, _ := FromFunction(func(x int) int { return x + 1 })
+	fail, _ := FromFunction(func(x int) (int, error) { return 0, errors.New("fail") })
+	tr, err := Chain(inc, Named("parse", fail))
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	tr, err = Apply(tr, SkipStreamers(func(t Transformer) Transformer { return LogErrorsTo(t, logger, LogOptions{}) }))
+	if err != nil {
+		t.Fatalf("can't apply middlewares: %v", err)
+	}
+	if outs := streamOutputs(t, tr, 1); len(outs) != 0 {
+		t.Fatalf("transform shouldn't output anything, got %v", outs)
+	}
+	if !strings.Contains(buf.String(), `"stage":"parse"`) {
+		t.Fatalf("error should be logged as the named stage, got %s", buf.String())
+	}
+}
@@ -0,0 +1,79 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Named gives a name to the transformer
+// the name is used instead of the type of the transformer in errors of Chain and InParallel,
+// it's added to the Path of TransformErrors and to names of stages in metrics and traces,
+// and it's shown in Describe
+// if t is a Streamer, so is the named transformer
+func Named(name string, t Transformer) Transformer {
+	named := namedTransformer{t, name}
+	if _, ok := t.(Streamer); ok {
+		return namedStreamer{named}
+	}
+	return named
+}
+
+type namedTransformer struct {
+	Transformer
+	name string
+}
+
+// OutputType is part of the OutputTyper interface
+func (n namedTransformer) OutputType() reflect.Type {
+	return OutputType(n.Transformer)
+}
+
+// children is part of the composite interface
+func (n namedTransformer) children() []Transformer {
+	return []Transformer{n.Transformer}
+}
+
+// stage is part of the composite interface
+func (n namedTransformer) stage(int) string {
+	return n.name
+}
+
+// withChildren is part of the composite interface
+func (n namedTransformer) withChildren(ts []Transformer) Transformer {
+	return Named(n.name, ts[0])
+}
+
+// Transform is part of the Transformer interface
+func (n namedTransformer) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	return withStage(n.name, newTransformError(v, callTransform(ctx, n.name, n.Transformer, v, ch)))
+}
+
+type namedStreamer struct {
+	namedTransformer
+}
+
+// TransformAll is part of the Streamer interface
+func (n namedStreamer) TransformAll(ctx context.Context, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	return withStage(n.name, all(ctx, n.name, n.Transformer, inCh, outCh))
+}
+
+// asNamed returns the named transformer t is, if it's one
+func asNamed(t Transformer) (namedTransformer, bool) {
+	switch n := t.(type) {
+	case namedTransformer:
+		return n, true
+	case namedStreamer:
+		return n.namedTransformer, true
+	}
+	return namedTransformer{}, false
+}
+
+// nameOf returns the quoted name of a named transformer, or the type of any other one
+// it's used to refer to transformers in errors
+func nameOf(t Transformer) string {
+	if n, ok := asNamed(t); ok {
+		return fmt.Sprintf("%q", n.name)
+	}
+	return fmt.Sprintf("%T", t)
+}
@@ -2,6 +2,7 @@ package transform
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 
 	"golang.org/x/sync/errgroup"
@@ -13,21 +14,33 @@ type parallel []Transformer
 // input type of all transformers should be the same, and that's the input type for this transformer
 // output type of all of them should be the same, and that's the output type of the transformer
 // transform is accomplished by running all given transformers in parallel on the same input data
-func InParallel(ts ...Transformer) Transformer {
+// returns an error if input or known output types of the transformers differ
+func InParallel(ts ...Transformer) (Transformer, error) {
 	switch len(ts) {
 	case 0:
-		panic("need at least one transformer to chain it")
+		return nil, fmt.Errorf("need at least one transformer to run it in parallel")
 	case 1:
-		return ts[0]
-	default:
-		typ := ts[0].InputType()
-		for _, t := range ts {
-			if t.InputType() != typ {
-				panic("not all transformers have the same input type")
-			}
+		return ts[0], nil
+	}
+
+	inputType := ts[0].InputType()
+	var outputType reflect.Type
+	outputIdx := -1
+	for i, t := range ts {
+		if typ := t.InputType(); typ != inputType {
+			return nil, fmt.Errorf("stage %d (%s) expects %s, but stage 0 (%s) expects %s", i, nameOf(t), typ, nameOf(ts[0]), inputType)
+		}
+		typ := OutputType(t)
+		if typ == nil {
+			continue
+		}
+		if outputType == nil {
+			outputType, outputIdx = typ, i
+		} else if typ != outputType {
+			return nil, fmt.Errorf("stage %d (%s) outputs %s, but stage %d (%s) outputs %s", i, nameOf(t), typ, outputIdx, nameOf(ts[outputIdx]), outputType)
 		}
-		return parallel(ts)
 	}
+	return parallel(ts), nil
 }
 
 // InputType is part of the Transformer interface
@@ -35,12 +48,41 @@ func (p parallel) InputType() reflect.Type {
 	return p[0].InputType()
 }
 
+// OutputType is part of the OutputTyper interface
+// output type is known only if all transformers know it
+func (p parallel) OutputType() reflect.Type {
+	typ := OutputType(p[0])
+	for _, t := range p[1:] {
+		if OutputType(t) == nil {
+			return nil
+		}
+	}
+	return typ
+}
+
+// children is part of the composite interface
+func (p parallel) children() []Transformer {
+	return p
+}
+
+// stage is part of the composite interface
+func (parallel) stage(i int) string {
+	return fmt.Sprintf("parallel[%d]", i)
+}
+
+// withChildren is part of the composite interface
+func (parallel) withChildren(ts []Transformer) Transformer {
+	return parallel(ts)
+}
+
 // Transform is part of the Transformer interface
 func (p parallel) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
 	group, ctx := errgroup.WithContext(ctx)
-	for _, t := range p {
-		t := t
-		group.Go(func() error { return t.Transform(ctx, v, ch) })
+	for i, t := range p {
+		i, t := i, t
+		group.Go(func() error {
+			return withStage(p.stage(i), newTransformError(v, callTransform(ctx, p.stage(i), t, v, ch)))
+		})
 	}
 	return group.Wait()
 }
@@ -0,0 +1,63 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestInParallel(t *testing.T) {
+	double, _ := FromFunction(func(x int) int { return 2 * x })
+	square, _ := FromFunction(func(x int) int { return x * x })
+
+	tr, err := InParallel(double, square)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	if OutputType(tr) != reflect.TypeOf(1) {
+		t.Fatalf("output type mismatch:\n\thave: %v\n\twant: int", OutputType(tr))
+	}
+
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		tr.Transform(context.Background(), 3, ch)
+	}()
+
+	var outs []int
+	for out := range ch {
+		outs = append(outs, out.(int))
+	}
+	sort.Ints(outs)
+	if want := []int{6, 9}; !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+}
+
+func TestInParallelTypes(t *testing.T) {
+	square, _ := FromFunction(func(x int) int { return x * x })
+	itoa, _ := FromFunction(func(x int) string { return fmt.Sprint(x) })
+	length, _ := FromFunction(func(s string) int { return len(s) })
+
+	for i, c := range []struct {
+		ts  []Transformer
+		err bool
+	}{
+		{ts: nil, err: true},
+		{ts: []Transformer{square, chainTest(1)}},
+		{ts: []Transformer{square, itoa}, err: true},
+		{ts: []Transformer{square, length}, err: true},
+	} {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			_, err := InParallel(c.ts...)
+			if err != nil && !c.err {
+				t.Fatalf("should be able to create transformer: %v", err)
+			}
+			if err == nil && c.err {
+				t.Fatalf("shouldn't be able to create transformer")
+			}
+		})
+	}
+}
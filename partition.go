@@ -0,0 +1,96 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"runtime/debug"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type partitioned struct {
+	Transformer
+	// key returns the partitioning key of a value
+	key func(interface{}) string
+	// number of workers
+	n int
+}
+
+// Partitioned creates a Transformer which transforms a stream of values with n workers
+// every value is sent to one of the workers by hashing its key, which means that
+// values with the same key are transformed one by one in the order they were received,
+// while values with different keys can be transformed in parallel
+// use it with All or in a Chain, since transforming a single value just calls the given transformer
+func Partitioned(t Transformer, key func(interface{}) string, n int) (Transformer, error) {
+	if key == nil {
+		return nil, fmt.Errorf("need a key function to partition values")
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("need at least 1 worker, got %d", n)
+	}
+	return partitioned{t, key, n}, nil
+}
+
+// OutputType is part of the OutputTyper interface
+func (p partitioned) OutputType() reflect.Type {
+	return OutputType(p.Transformer)
+}
+
+// TransformAll is part of the Streamer interface
+// if any of the workers fails, the context of all other workers is cancelled
+func (p partitioned) TransformAll(ctx context.Context, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	group, ctx := errgroup.WithContext(ctx)
+
+	partitions := make([]chan interface{}, p.n)
+	for i := range partitions {
+		partitions[i] = make(chan interface{})
+		partition := partitions[i]
+		group.Go(func() error { return All(ctx, p.Transformer, partition, outCh) })
+	}
+
+	group.Go(func() error {
+		defer func() {
+			for _, partition := range partitions {
+				close(partition)
+			}
+		}()
+		for {
+			select {
+			case v, more := <-inCh:
+				if !more {
+					return nil
+				}
+				i, err := p.partition(v)
+				if err != nil {
+					return err
+				}
+				select {
+				case partitions[i] <- v:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+
+	return group.Wait()
+}
+
+// partition returns the index of the worker the value is sent to
+// a panic in the key function is returned as a TransformError if RecoverPanics is set
+func (p partitioned) partition(v interface{}) (i int, err error) {
+	if RecoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				err = newTransformError(v, &PanicError{Value: r, Stack: debug.Stack()})
+			}
+		}()
+	}
+	h := fnv.New32a()
+	h.Write([]byte(p.key(v)))
+	return int(h.Sum32() % uint32(p.n)), nil
+}
@@ -0,0 +1,85 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type partitionTestEvent struct {
+	User string
+	Seq  int
+}
+
+func TestPartitioned(t *testing.T) {
+	const numUsers, numEvents = 10, 100
+
+	identity, _ := FromFunction(func(e partitionTestEvent) (partitionTestEvent, error) {
+		if e.Seq < 0 {
+			return e, fmt.Errorf("negative sequence")
+		}
+		return e, nil
+	})
+	key := func(v interface{}) string { return v.(partitionTestEvent).User }
+
+	if _, err := Partitioned(identity, key, 0); err == nil {
+		t.Fatalf("shouldn't be able to create transformer without workers")
+	}
+	if _, err := Partitioned(identity, nil, 4); err == nil {
+		t.Fatalf("shouldn't be able to create transformer without a key function")
+	}
+	tr, err := Partitioned(identity, key, 4)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+
+	run := func(events []partitionTestEvent) ([]partitionTestEvent, error) {
+		inCh := make(chan interface{})
+		go func() {
+			defer close(inCh)
+			for _, e := range events {
+				inCh <- e
+			}
+		}()
+
+		outCh := make(chan interface{})
+		errCh := make(chan error, 1)
+		go func() {
+			defer close(outCh)
+			errCh <- All(context.Background(), tr, inCh, outCh)
+		}()
+
+		var outs []partitionTestEvent
+		for out := range outCh {
+			outs = append(outs, out.(partitionTestEvent))
+		}
+		return outs, <-errCh
+	}
+
+	var events []partitionTestEvent
+	for seq := 0; seq < numEvents; seq++ {
+		for u := 0; u < numUsers; u++ {
+			events = append(events, partitionTestEvent{fmt.Sprintf("user-%d", u), seq})
+		}
+	}
+
+	outs, err := run(events)
+	if err != nil {
+		t.Fatalf("transform shouldn't error out, got %v", err)
+	}
+	if len(outs) != len(events) {
+		t.Fatalf("unexpected number of outputs\n\thave: %d\n\twant: %d", len(outs), len(events))
+	}
+	next := map[string]int{}
+	for _, e := range outs {
+		if e.Seq != next[e.User] {
+			t.Fatalf("events of %s are out of order: got %d, expecting %d", e.User, e.Seq, next[e.User])
+		}
+		next[e.User]++
+	}
+
+	events = append(events, partitionTestEvent{"user-0", -1})
+	if _, err := run(events); err == nil {
+		t.Fatalf("transform should error out, but didn't")
+	}
+}
@@ -0,0 +1,420 @@
+// Package pipeline compiles declarative pipeline definitions into transformers
+// definitions are written in YAML, or JSON since it's valid YAML, and look like this:
+//
+//	types:
+//	  visit: {user_id: int, time: time}
+//	input: event
+//	pipeline:
+//	  chain:
+//	    - use: parse
+//	      params: {strict: true}
+//	    - parallel:
+//	        - collapse: [user_id, time]
+//	        - collapse: [user_id, country]
+//	    - name: by type
+//	      route:
+//	        - expand: {type: visit, fields: [user_id, time]}
+//	        - use: geo
+//	      default: discard
+//
+// types declares struct types by their fields, or names other types, e.g. ids: []int
+// field names are used as hive tags, and fields are either type expressions or nested structs
+// type expressions are basic go types, time for time.Time, declared or registered types, and *T, []T or map[string]T of those
+// type expressions which start with [ or * have to be quoted in YAML
+// input is a type expression of the input type of the pipeline
+// pipeline is the root node, every node is one of:
+//	1) chain: [nodes] -> transform.Chain
+//	2) parallel: [nodes] -> transform.InParallel
+//	3) route: [nodes], default: node -> transform.RouteType, without a default unmatched values are errors
+//	4) use: name, params: {...} -> transform.Registry.Build, a plain name is a shorthand for use without params
+//	5) collapse: [fields] or collapse: {type: type, fields: [fields]} -> transform.NewStructCollapser
+//	6) expand: {type: type, fields: [fields]} -> transform.NewStructExpander
+//	7) discard -> transform.Discard
+// every node can have a name, which names the transformer with transform.Named
+// collapse infers its type from the output type of the previous node if the type isn't set
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/n1chre/transform"
+	"gopkg.in/yaml.v3"
+)
+
+// Error is returned when a definition is invalid
+type Error struct {
+	// File is the file which contains the definition, empty if it wasn't read from a file
+	File string
+	// Line and Column are the position of the invalid node, zero if it's unknown
+	Line, Column int
+	// Path is the path to the invalid node, e.g. pipeline/chain[1]/parallel[0]
+	Path string
+	// Err is the cause of the error
+	Err error
+}
+
+// Error is part of the error interface
+func (e *Error) Error() string {
+	pos := e.File
+	if e.Line > 0 {
+		if pos != "" {
+			pos += ":"
+		}
+		pos += fmt.Sprintf("%d:%d", e.Line, e.Column)
+	}
+	if e.Path != "" {
+		if pos != "" {
+			pos += ": "
+		}
+		pos += e.Path
+	}
+	if pos == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", pos, e.Err)
+}
+
+// Unwrap returns the cause of the error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CompileFile compiles the definition in the given file
+// see Compile for more details
+func CompileFile(path string, registry *transform.Registry) (transform.Transformer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Compile(data, registry)
+	if e, ok := err.(*Error); ok {
+		e.File = path
+	}
+	return t, err
+}
+
+// Compile compiles the definition into a transformer
+// transformers and types are looked up in the given registry, or in transform.DefaultRegistry if it's nil
+// returned errors are of type *Error
+func Compile(data []byte, registry *transform.Registry) (transform.Transformer, error) {
+	if registry == nil {
+		registry = transform.DefaultRegistry
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &Error{Err: err}
+	}
+	if len(doc.Content) == 0 {
+		return nil, &Error{Err: fmt.Errorf("definition is empty")}
+	}
+	c := compiler{registry: registry, declared: map[string]reflect.Type{}}
+	return c.definition(doc.Content[0])
+}
+
+type compiler struct {
+	registry *transform.Registry
+	// declared are the types declared in the types section of the definition
+	declared map[string]reflect.Type
+}
+
+// errorf creates an error at the position of the node n with the given path
+func errorf(n *yaml.Node, path string, format string, args ...interface{}) error {
+	return &Error{Line: n.Line, Column: n.Column, Path: path, Err: fmt.Errorf(format, args...)}
+}
+
+// fields returns the values of mapping n by their keys
+// returns an error if n isn't a mapping or it has a key which isn't allowed
+func fields(n *yaml.Node, path string, allowed ...string) (map[string]*yaml.Node, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, errorf(n, path, "expected a mapping")
+	}
+	m := make(map[string]*yaml.Node, len(n.Content)/2)
+	for i := 0; i < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if !contains(allowed, k.Value) {
+			return nil, errorf(k, path, "unknown key %q", k.Value)
+		}
+		if _, ok := m[k.Value]; ok {
+			return nil, errorf(k, path, "duplicate key %q", k.Value)
+		}
+		m[k.Value] = v
+	}
+	return m, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *compiler) definition(n *yaml.Node) (transform.Transformer, error) {
+	m, err := fields(n, "", "types", "input", "pipeline")
+	if err != nil {
+		return nil, err
+	}
+
+	if typesNode, ok := m["types"]; ok {
+		if err := c.types(typesNode); err != nil {
+			return nil, err
+		}
+	}
+
+	var in reflect.Type
+	if inputNode, ok := m["input"]; ok {
+		if in, err = c.typ(inputNode, "input"); err != nil {
+			return nil, err
+		}
+	}
+
+	root, ok := m["pipeline"]
+	if !ok {
+		return nil, errorf(n, "", "pipeline isn't defined")
+	}
+	t, err := c.node(root, "pipeline", in)
+	if err != nil {
+		return nil, err
+	}
+	// the same rule Chain uses, e.g. a route at the root expects interface{}, so it accepts any input
+	if in != nil && in.Kind() != reflect.Interface && !in.AssignableTo(t.InputType()) {
+		return nil, errorf(root, "pipeline", "pipeline expects %s, but input is %s", t.InputType(), in)
+	}
+	return t, nil
+}
+
+// stringList returns the values of the sequence n of scalars
+func stringList(n *yaml.Node, path string) ([]string, error) {
+	if n.Kind != yaml.SequenceNode {
+		return nil, errorf(n, path, "expected a list")
+	}
+	ss := make([]string, len(n.Content))
+	for i, s := range n.Content {
+		if s.Kind != yaml.ScalarNode {
+			return nil, errorf(s, path, "expected a string")
+		}
+		ss[i] = s.Value
+	}
+	return ss, nil
+}
+
+// node compiles the node n
+// in is the type of values the node will transform, nil if it's unknown
+func (c *compiler) node(n *yaml.Node, path string, in reflect.Type) (transform.Transformer, error) {
+	if n.Kind == yaml.ScalarNode {
+		if n.Value == "discard" {
+			return transform.Discard, nil
+		}
+		return c.use(n, nil, path)
+	}
+
+	m, err := fields(n, path, "name", "chain", "parallel", "route", "default", "use", "params", "collapse", "expand")
+	if err != nil {
+		return nil, err
+	}
+
+	var kinds []string
+	for _, kind := range []string{"chain", "parallel", "route", "use", "collapse", "expand"} {
+		if _, ok := m[kind]; ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) != 1 {
+		return nil, errorf(n, path, "node should have exactly one of chain, parallel, route, use, collapse or expand, got %v", kinds)
+	}
+	kind := kinds[0]
+	if v, ok := m["params"]; ok && kind != "use" {
+		return nil, errorf(v, path, "params are only allowed with use")
+	}
+	if v, ok := m["default"]; ok && kind != "route" {
+		return nil, errorf(v, path, "default is only allowed with route")
+	}
+
+	var t transform.Transformer
+	switch kind {
+	case "chain":
+		t, err = c.chain(m[kind], path+"/chain", in)
+	case "parallel":
+		t, err = c.parallel(m[kind], path+"/parallel", in)
+	case "route":
+		t, err = c.route(m[kind], m["default"], path+"/route", in)
+	case "use":
+		t, err = c.use(m[kind], m["params"], path)
+	case "collapse":
+		t, err = c.collapse(m[kind], path+"/collapse", in)
+	case "expand":
+		t, err = c.expand(m[kind], path+"/expand")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if nameNode, ok := m["name"]; ok {
+		if nameNode.Kind != yaml.ScalarNode || nameNode.Value == "" {
+			return nil, errorf(nameNode, path, "expected a name")
+		}
+		t = transform.Named(nameNode.Value, t)
+	}
+	return t, nil
+}
+
+// nodes compiles all nodes of the sequence n, and calls next with nodes compiled so far after every one
+// next returns the input type of the next node
+func (c *compiler) nodes(n *yaml.Node, path string, in reflect.Type, next func(ts []transform.Transformer) (reflect.Type, error)) ([]transform.Transformer, error) {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		return nil, errorf(n, path, "expected a non-empty list")
+	}
+	ts := make([]transform.Transformer, len(n.Content))
+	for i, child := range n.Content {
+		t, err := c.node(child, fmt.Sprintf("%s[%d]", path, i), in)
+		if err != nil {
+			return nil, err
+		}
+		ts[i] = t
+		if in, err = next(ts[:i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return ts, nil
+}
+
+func (c *compiler) chain(n *yaml.Node, path string, in reflect.Type) (transform.Transformer, error) {
+	ts, err := c.nodes(n, path, in, func(ts []transform.Transformer) (reflect.Type, error) {
+		i, t := len(ts)-1, ts[len(ts)-1]
+		if i > 0 {
+			// chain every stage with the previous one to report a mismatch at the stage which causes it
+			if _, err := transform.Chain(ts[i-1], t); err != nil {
+				return nil, errorf(n.Content[i], fmt.Sprintf("%s[%d]", path, i), "%v", err)
+			}
+		}
+		return transform.OutputType(t), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	t, err := transform.Chain(ts...)
+	if err != nil {
+		return nil, errorf(n, path, "%v", err)
+	}
+	return t, nil
+}
+
+func (c *compiler) parallel(n *yaml.Node, path string, in reflect.Type) (transform.Transformer, error) {
+	ts, err := c.nodes(n, path, in, func([]transform.Transformer) (reflect.Type, error) {
+		return in, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	t, err := transform.InParallel(ts...)
+	if err != nil {
+		return nil, errorf(n, path, "%v", err)
+	}
+	return t, nil
+}
+
+func (c *compiler) route(n, fallbackNode *yaml.Node, path string, in reflect.Type) (transform.Transformer, error) {
+	// branches are picked by their input type, so they don't get the input type of the route
+	ts, err := c.nodes(n, path, nil, func([]transform.Transformer) (reflect.Type, error) {
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var fallback transform.Transformer
+	if fallbackNode != nil {
+		if fallback, err = c.node(fallbackNode, path+"[default]", in); err != nil {
+			return nil, err
+		}
+	}
+	t, err := transform.RouteType(fallback, ts...)
+	if err != nil {
+		return nil, errorf(n, path, "%v", err)
+	}
+	return t, nil
+}
+
+func (c *compiler) use(n, paramsNode *yaml.Node, path string) (transform.Transformer, error) {
+	if n.Kind != yaml.ScalarNode {
+		return nil, errorf(n, path, "expected a name of a registered transformer")
+	}
+
+	var params map[string]interface{}
+	if paramsNode != nil {
+		if err := paramsNode.Decode(&params); err != nil {
+			return nil, errorf(paramsNode, path, "invalid params: %v", err)
+		}
+	}
+	t, err := c.registry.Build(n.Value, params)
+	if err != nil {
+		return nil, errorf(n, path, "%v", err)
+	}
+	return t, nil
+}
+
+func (c *compiler) collapse(n *yaml.Node, path string, in reflect.Type) (transform.Transformer, error) {
+	typ, names := in, n
+	if n.Kind == yaml.MappingNode {
+		m, err := fields(n, path, "type", "fields")
+		if err != nil {
+			return nil, err
+		}
+		if typeNode, ok := m["type"]; ok {
+			if typ, err = c.typ(typeNode, path); err != nil {
+				return nil, err
+			}
+		}
+		var ok bool
+		if names, ok = m["fields"]; !ok {
+			return nil, errorf(n, path, "fields aren't defined")
+		}
+	}
+	if typ == nil {
+		return nil, errorf(n, path, "input type is unknown, set the type of collapse")
+	}
+
+	ss, err := stringList(names, path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := transform.NewStructCollapser(typ, ss)
+	if err != nil {
+		return nil, errorf(names, path, "%v", err)
+	}
+	return t, nil
+}
+
+func (c *compiler) expand(n *yaml.Node, path string) (transform.Transformer, error) {
+	m, err := fields(n, path, "type", "fields")
+	if err != nil {
+		return nil, err
+	}
+	typeNode, ok := m["type"]
+	if !ok {
+		return nil, errorf(n, path, "type isn't defined")
+	}
+	names, ok := m["fields"]
+	if !ok {
+		return nil, errorf(n, path, "fields aren't defined")
+	}
+
+	typ, err := c.typ(typeNode, path)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := stringList(names, path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := transform.NewStructExpander(typ, ss)
+	if err != nil {
+		return nil, errorf(names, path, "%v", err)
+	}
+	return t, nil
+}
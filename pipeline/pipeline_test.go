@@ -0,0 +1,215 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/n1chre/transform"
+)
+
+type event struct {
+	UserID  int `hive:"user_id"`
+	Country string
+	Time    int
+}
+
+type visit struct {
+	UserID int `hive:"user_id"`
+	Time   int
+}
+
+func testRegistry(t *testing.T) *transform.Registry {
+	t.Helper()
+	r := transform.NewRegistry()
+	r.MustRegister("shift", func(p struct{ By int }) (transform.Transformer, error) {
+		return transform.FromFunction(func(e event) event { e.Time += p.By; return e })
+	})
+	r.MustRegister("country", func() (transform.Transformer, error) {
+		return transform.FromFunction(func(e event) string { return e.Country })
+	})
+	for name, typ := range map[string]reflect.Type{"event": reflect.TypeOf(event{}), "visit": reflect.TypeOf(visit{})} {
+		if err := r.RegisterType(name, typ); err != nil {
+			t.Fatalf("can't register type: %v", err)
+		}
+	}
+	return r
+}
+
+// transformAll transforms all inputs with the transformer and returns the outputs formatted with %v
+func transformAll(t *testing.T, tr transform.Transformer, inputs ...interface{}) []string {
+	t.Helper()
+	in, out := make(chan interface{}, len(inputs)), make(chan interface{}, 100)
+	for _, v := range inputs {
+		in <- v
+	}
+	close(in)
+	if err := transform.All(context.Background(), tr, in, out); err != nil {
+		t.Fatalf("can't transform: %v", err)
+	}
+	close(out)
+
+	var outs []string
+	for v := range out {
+		outs = append(outs, fmt.Sprintf("%v", v))
+	}
+	return outs
+}
+
+// sessionType is the type declared as session in TestCompile
+var sessionType = reflect.StructOf([]reflect.StructField{
+	{Name: "Id", Type: reflect.TypeOf(""), Tag: `hive:"id"`},
+	{Name: "Visits", Type: reflect.TypeOf([]visit{}), Tag: `hive:"visits"`},
+	{Name: "User", Type: reflect.StructOf([]reflect.StructField{
+		{Name: "UserId", Type: reflect.TypeOf(0), Tag: `hive:"user_id"`},
+		{Name: "Country", Type: reflect.TypeOf((*string)(nil)), Tag: `hive:"country"`},
+	}), Tag: `hive:"user"`},
+})
+
+func TestCompile(t *testing.T) {
+	e := event{UserID: 1, Country: "hr", Time: 10}
+
+	cases := []struct {
+		def     string
+		inputs  []interface{}
+		outputs []string
+		err     string // expected in the error message, if not empty
+	}{
+		{
+			def:     "input: event\npipeline: country",
+			inputs:  []interface{}{e},
+			outputs: []string{"hr"},
+		},
+		{
+			def: `
+input: event
+pipeline:
+  chain:
+    - use: shift
+      params: {by: 5}
+    - collapse: [user_id, time]
+    - expand: {type: visit, fields: [user_id, time]}
+`,
+			inputs:  []interface{}{e},
+			outputs: []string{"{1 15}"},
+		},
+		{
+			def:     `{"input": "event", "pipeline": {"parallel": ["country", {"name": "c", "use": "country"}]}}`,
+			inputs:  []interface{}{e},
+			outputs: []string{"hr", "hr"},
+		},
+		{
+			def: `
+pipeline:
+  route:
+    - collapse: {type: event, fields: [user_id, time]}
+    - collapse: {type: visit, fields: [user_id, time]}
+  default: discard
+`,
+			inputs:  []interface{}{e, visit{2, 20}, "dropped"},
+			outputs: []string{"{1 10}", "{2 20}"},
+		},
+		{
+			def: `
+input: event
+pipeline:
+  route:
+    - country
+`,
+			inputs:  []interface{}{e},
+			outputs: []string{"hr"},
+		},
+		{
+			def: "input: visit\npipeline: country\n",
+			err: "2:11: pipeline: pipeline expects",
+		},
+		{
+			def: "input: event\npipeline:\n  chain:\n    - country\n    - shift\n",
+			err: "5:7: pipeline/chain[1]: stage 0 (\"country\") outputs string",
+		},
+		{
+			def: "input: event\npipeline:\n  collapse: [user_id, nope]\n",
+			err: "3:13: pipeline/collapse:",
+		},
+		{
+			def: "input: event\npipeline:\n  use: shift\n  params: {by: 1, to: 2}\n",
+			err: "3:8: pipeline: invalid parameters of \"shift\"",
+		},
+		{
+			def: "input: event\npipeline:\n  chain: [country]\n  use: shift\n",
+			err: "3:3: pipeline: node should have exactly one of",
+		},
+		{
+			def: "pipeline:\n  collapse: [user_id]\n",
+			err: "2:13: pipeline/collapse: input type is unknown",
+		},
+		{
+			def: "input: person\npipeline: country\n",
+			err: "1:8: input: type \"person\" isn't declared or registered",
+		},
+		{
+			def: "input: event\npipeline: {chain: []}\n",
+			err: "2:19: pipeline/chain: expected a non-empty list",
+		},
+		{
+			def: "input: event\npipelines: country\n",
+			err: "2:1: unknown key \"pipelines\"",
+		},
+		{
+			def: `
+types:
+  session:
+    id: string
+    visits: "[]visit"
+    user: {user_id: int, country: "*string"}
+input: session
+pipeline:
+  collapse: [visits, id]
+`,
+			inputs:  []interface{}{reflect.New(sessionType).Elem().Interface()},
+			outputs: []string{"{[] }"},
+		},
+		{
+			def: "types:\n  visits: \"[]visit\"\n  ids: \"[]int\"\ninput: visits\npipeline: {collapse: [user_id]}\n",
+			err: "5:22: pipeline/collapse: can't build subtype",
+		},
+		{
+			def: "types:\n  visit: {id: int}\npipeline: discard\n",
+			err: "2:3: types/visit: type \"visit\" is already declared or registered",
+		},
+		{
+			def: "types:\n  t: {a_b: int, a__b: int}\npipeline: discard\n",
+			err: "2:17: types/t/a__b: field \"a__b\" clashes with field \"a_b\"",
+		},
+		{
+			def: "input: event\npipeline: {use: shift, default: country}\n",
+			err: "default is only allowed with route",
+		},
+	}
+
+	r := testRegistry(t)
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			tr, err := Compile([]byte(c.def), r)
+			if err != nil {
+				if c.err == "" || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, ok := err.(*Error); !ok {
+					t.Fatalf("expecting an *Error, got %T", err)
+				}
+				return
+			}
+			if c.err != "" {
+				t.Fatalf("expecting an error which contains %q", c.err)
+			}
+
+			outputs := transformAll(t, tr, c.inputs...)
+			if !reflect.DeepEqual(outputs, c.outputs) {
+				t.Fatalf("outputs mismatch\n\thave:\t%v\n\twant:\t%v", outputs, c.outputs)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// basicTypes are the types which can be used in definitions without declaring or registering them
+var basicTypes = map[string]reflect.Type{
+	"bool":    reflect.TypeOf(false),
+	"int":     reflect.TypeOf(int(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+	"string":  reflect.TypeOf(""),
+	"time":    reflect.TypeOf(time.Time{}),
+}
+
+// types declares all types in the mapping n, in order, so that a type can use the ones declared before it
+func (c *compiler) types(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return errorf(n, "types", "expected a mapping of type names to types")
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		path := "types/" + k.Value
+		if _, err := c.parseType(k.Value); err == nil {
+			return errorf(k, path, "type %q is already declared or registered", k.Value)
+		}
+		typ, err := c.typ(v, path)
+		if err != nil {
+			return err
+		}
+		c.declared[k.Value] = typ
+	}
+	return nil
+}
+
+// typ returns the type described by n
+// it's either a type expression, e.g. []int or a name of a declared type, or a mapping of field names to types
+func (c *compiler) typ(n *yaml.Node, path string) (reflect.Type, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		typ, err := c.parseType(n.Value)
+		if err != nil {
+			return nil, errorf(n, path, "%v", err)
+		}
+		return typ, nil
+	case yaml.MappingNode:
+		return c.structType(n, path)
+	default:
+		return nil, errorf(n, path, "expected a type")
+	}
+}
+
+// parseType parses a type expression
+// it's one of the basic types, a declared or registered type, or *T, []T or map[string]T of any of those
+func (c *compiler) parseType(s string) (reflect.Type, error) {
+	var wrap func(reflect.Type) reflect.Type
+	var elem string
+	switch {
+	case strings.HasPrefix(s, "*"):
+		wrap, elem = reflect.PtrTo, s[1:]
+	case strings.HasPrefix(s, "[]"):
+		wrap, elem = reflect.SliceOf, s[2:]
+	case strings.HasPrefix(s, "map[string]"):
+		wrap = func(t reflect.Type) reflect.Type { return reflect.MapOf(basicTypes["string"], t) }
+		elem = strings.TrimPrefix(s, "map[string]")
+	}
+	if wrap != nil {
+		typ, err := c.parseType(elem)
+		if err != nil {
+			return nil, err
+		}
+		return wrap(typ), nil
+	}
+
+	if typ, ok := basicTypes[s]; ok {
+		return typ, nil
+	}
+	if typ, ok := c.declared[s]; ok {
+		return typ, nil
+	}
+	if typ, ok := c.registry.Type(s); ok {
+		return typ, nil
+	}
+	return nil, fmt.Errorf("type %q isn't declared or registered", s)
+}
+
+// structType creates a struct type from the mapping n of field names to types
+// every field gets a hive tag with its name, so that transform.GetStructFieldName returns it
+func (c *compiler) structType(n *yaml.Node, path string) (reflect.Type, error) {
+	fields := make([]reflect.StructField, 0, len(n.Content)/2)
+	names := map[string]string{} // go name -> field name
+	for i := 0; i < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		fieldPath := path + "/" + k.Value
+
+		name := exportedName(k.Value)
+		if other, ok := names[name]; ok {
+			return nil, errorf(k, fieldPath, "field %q clashes with field %q", k.Value, other)
+		}
+		names[name] = k.Value
+
+		typ, err := c.typ(v, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, reflect.StructField{
+			Name: name,
+			Type: typ,
+			Tag:  reflect.StructTag(fmt.Sprintf("hive:%q", k.Value)),
+		})
+	}
+	if len(fields) == 0 {
+		return nil, errorf(n, path, "struct should have at least one field")
+	}
+	return reflect.StructOf(fields), nil
+}
+
+// exportedName returns a name of an exported go field for the field name, e.g. UserId for user_id
+func exportedName(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range strings.ToLower(name) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	s := sb.String()
+	if s == "" || !unicode.IsUpper([]rune(s)[0]) {
+		s = "F" + s
+	}
+	return s
+}
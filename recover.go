@@ -0,0 +1,95 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+)
+
+// RecoverPanics makes combinators (All, Chain, InParallel, routers, error handlers, ...) recover from panics
+// of transformers they call, and return them as TransformErrors, the same way Recover does
+// it's enabled by default, disable it to let panics crash the program
+var RecoverPanics = true
+
+// PanicError is the cause of a TransformError returned when a transformer panics
+type PanicError struct {
+	// Value is the value panic was called with
+	Value interface{}
+	// Stack is the stack trace of the goroutine which panicked
+	Stack []byte
+}
+
+// Error is part of the error interface
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Recover wraps the given transformer in a way that panics in it are returned as TransformErrors
+// cause of the error is a PanicError, and the input is the value which was being transformed
+// it recovers from panics even if RecoverPanics is disabled
+// if t is a Streamer, so is the wrapped transformer, but inputs of panics in TransformAll are unknown (nil)
+func Recover(t Transformer) Transformer {
+	recovering := recoveringTransformer{t}
+	if _, ok := t.(Streamer); ok {
+		return recoveringStreamer{recovering}
+	}
+	return recovering
+}
+
+type recoveringTransformer struct {
+	Transformer
+}
+
+// OutputType is a part of the OutputTyper interface
+func (t recoveringTransformer) OutputType() reflect.Type {
+	return OutputType(t.Transformer)
+}
+
+// Transform is a part of the Transformer interface
+func (t recoveringTransformer) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	return safeTransform(ctx, t.Transformer, v, ch, true)
+}
+
+type recoveringStreamer struct {
+	recoveringTransformer
+}
+
+// TransformAll is a part of the Streamer interface
+func (t recoveringStreamer) TransformAll(ctx context.Context, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	return safeTransformAll(ctx, t.Transformer.(Streamer), inCh, outCh, true)
+}
+
+// callTransform is used by combinators to call transformers they are built from
+// stage is the name of the transformer in the combinator, it's traced if it isn't empty
+func callTransform(ctx context.Context, stage string, t Transformer, v interface{}, ch chan<- interface{}) error {
+	ctx, end := startSpan(ctx, stage, v)
+	err := safeTransform(ctx, t, v, ch, RecoverPanics)
+	end(err)
+	return err
+}
+
+// safeTransform calls t.Transform, and returns a panic in it as a TransformError if recoverPanics is set
+func safeTransform(ctx context.Context, t Transformer, v interface{}, ch chan<- interface{}, recoverPanics bool) (err error) {
+	if recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				err = newTransformError(v, &PanicError{Value: r, Stack: debug.Stack()})
+			}
+		}()
+	}
+	return t.Transform(ctx, v, ch)
+}
+
+// safeTransformAll calls s.TransformAll, and returns a panic in it as a TransformError if recoverPanics is set
+// the input which caused the panic is unknown, so it's nil
+func safeTransformAll(ctx context.Context, s Streamer, inCh <-chan interface{}, outCh chan<- interface{}, recoverPanics bool) (err error) {
+	if recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				err = newTransformError(nil, &PanicError{Value: r, Stack: debug.Stack()})
+			}
+		}()
+	}
+	return s.TransformAll(ctx, inCh, outCh)
+}
@@ -0,0 +1,113 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	div, _ := FromFunction(func(x int) int { return 100 / x })
+	dec, _ := FromFunction(func(x int) int { return x - 1 })
+
+	var panicErr *PanicError
+	var te *TransformError
+
+	// works even without RecoverPanics
+	defer func(recoverPanics bool) { RecoverPanics = recoverPanics }(RecoverPanics)
+	RecoverPanics = false
+
+	_, err := collectOutputs(t, Recover(div), 0)
+	if !errors.As(err, &panicErr) || !errors.As(err, &te) {
+		t.Fatalf("expecting a TransformError caused by panic, got %v", err)
+	}
+	if te.Input != 0 || !strings.Contains(string(panicErr.Stack), "TestRecover") {
+		t.Fatalf("error doesn't have the input or the stack trace: %v", err)
+	}
+
+	// combinators recover by default
+	RecoverPanics = true
+
+	tr, err := Chain(dec, div)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+	_, err = collectOutputs(t, tr, 1)
+	if !errors.As(err, &panicErr) || !errors.As(err, &te) {
+		t.Fatalf("expecting a TransformError caused by panic, got %v", err)
+	}
+	if te.Input != 0 || !reflect.DeepEqual(te.Path, []string{"chain[1]"}) {
+		t.Fatalf("unexpected input or path: %v", err)
+	}
+
+	var handled error
+	logged := WithErrorHandler(div, func(err error) error {
+		handled = err
+		return nil
+	})
+	if err := logged.Transform(context.Background(), 0, nil); err != nil || !errors.As(handled, &panicErr) {
+		t.Fatalf("panic should be passed to the error handler, got %v", handled)
+	}
+
+	// struct transformer given a wrong type
+	collapser, err := NewStructCollapser(reflect.TypeOf(struct{ I int }{}), []string{"i"})
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := collectOutputs(t, LogErrorsTo(collapser, logger, LogOptions{}), "not a struct"); err != nil {
+		t.Fatalf("error should be logged, got %v", err)
+	}
+}
+
+type panicStreamer struct {
+	chainTest
+}
+
+func (panicStreamer) TransformAll(context.Context, <-chan interface{}, chan<- interface{}) error {
+	panic("stream")
+}
+
+func TestRecoverStreamer(t *testing.T) {
+	tr := Recover(panicStreamer{})
+	if _, ok := tr.(Streamer); !ok {
+		t.Fatalf("recovering a Streamer should return a Streamer, got %T", tr)
+	}
+
+	inCh, outCh := make(chan interface{}), make(chan interface{})
+	close(inCh)
+	err := All(context.Background(), tr, inCh, outCh)
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "stream" {
+		t.Fatalf("expecting a PanicError, got %v", err)
+	}
+}
+
+func TestRecoverStreams(t *testing.T) {
+	var pe *PanicError
+
+	// a streamer given a wrong type
+	batch, err := Batch(reflect.TypeOf(1), 2, 0)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	if err := All(context.Background(), batch, channelOf("1", "x"), make(chan interface{}, 2)); !errors.As(err, &pe) {
+		t.Fatalf("expecting a PanicError, got %v", err)
+	}
+
+	// a key function which panics
+	identity, _ := FromFunction(func(s string) string { return s })
+	partitioned, err := Partitioned(identity, func(v interface{}) string { return v.(string) }, 2)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	var te *TransformError
+	err = All(context.Background(), partitioned, channelOf(1), make(chan interface{}, 1))
+	if !errors.As(err, &pe) || !errors.As(err, &te) || te.Input != 1 {
+		t.Fatalf("expecting a TransformError of 1 caused by panic, got %v", err)
+	}
+}
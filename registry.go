@@ -0,0 +1,202 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Registry maps names to constructors of transformers, so that transformers can be built by name
+// it also maps names to types, so that types can be referred to by name, e.g. in pipeline definitions
+// it's safe for concurrent use
+type Registry struct {
+	mu           sync.RWMutex
+	constructors map[string]constructor
+	types        map[string]reflect.Type
+}
+
+type constructor struct {
+	f reflect.Value
+	// paramType is the type of constructor's parameter, nil if it doesn't take any
+	paramType reflect.Type
+}
+
+var transformerType = reflect.TypeOf((*Transformer)(nil)).Elem()
+
+// DefaultRegistry is the registry used by Register
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		constructors: map[string]constructor{},
+		types:        map[string]reflect.Type{},
+	}
+}
+
+// Register adds the constructor to DefaultRegistry
+func Register(name string, constructor interface{}) error {
+	return DefaultRegistry.Register(name, constructor)
+}
+
+// Register adds a constructor of transformers with the given name
+// supported constructors are:
+//	1) func() (Transformer, error)
+//	2) func(P) (Transformer, error)
+// where P are parameters of the constructor, usually a struct, which are decoded from a map in Build
+// returns an error if the name is already registered or the constructor isn't supported
+func (r *Registry) Register(name string, constructor interface{}) error {
+	if name == "" {
+		return fmt.Errorf("name can't be empty")
+	}
+	c, err := newConstructor(constructor)
+	if err != nil {
+		return fmt.Errorf("can't register %q: %v", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.constructors[name]; ok {
+		return fmt.Errorf("%q is already registered", name)
+	}
+	r.constructors[name] = c
+	return nil
+}
+
+// MustRegister is the same as Register, but panics on errors
+// it's meant to be used in init functions
+func (r *Registry) MustRegister(name string, constructor interface{}) {
+	if err := r.Register(name, constructor); err != nil {
+		panic(err)
+	}
+}
+
+func newConstructor(f interface{}) (constructor, error) {
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return constructor{}, fmt.Errorf("constructor should be a function, got %T", f)
+	}
+	ft := fv.Type()
+
+	if ft.IsVariadic() || ft.NumIn() > 1 {
+		return constructor{}, fmt.Errorf("constructor should take at most one parameter, got %s", ft)
+	}
+	if ft.NumOut() != 2 || ft.Out(0) != transformerType || ft.Out(1) != errorType {
+		return constructor{}, fmt.Errorf("constructor should return (Transformer, error), got %s", ft)
+	}
+
+	c := constructor{f: fv}
+	if ft.NumIn() == 1 {
+		c.paramType = ft.In(0)
+	}
+	return c, nil
+}
+
+// RegisterType adds the type with the given name
+// returns an error if a type with the same name is already registered
+func (r *Registry) RegisterType(name string, typ reflect.Type) error {
+	if name == "" {
+		return fmt.Errorf("name can't be empty")
+	}
+	if typ == nil {
+		return fmt.Errorf("can't register nil type as %q", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[name]; ok {
+		return fmt.Errorf("type %q is already registered", name)
+	}
+	r.types[name] = typ
+	return nil
+}
+
+// Type returns the type registered with the given name
+func (r *Registry) Type(name string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	typ, ok := r.types[name]
+	return typ, ok
+}
+
+// Names returns sorted names of all registered constructors
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.constructors))
+	for name := range r.constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build creates a transformer with the constructor registered under the name
+// params are decoded into constructor's parameter as if they were JSON, unknown params are an error
+// the transformer is Named with the given name
+func (r *Registry) Build(name string, params map[string]interface{}) (Transformer, error) {
+	r.mu.RLock()
+	c, ok := r.constructors[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%q isn't registered", name)
+	}
+
+	var in []reflect.Value
+	if c.paramType == nil {
+		if len(params) > 0 {
+			return nil, fmt.Errorf("%q doesn't take parameters", name)
+		}
+	} else {
+		p, err := decodeParams(params, c.paramType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters of %q: %v", name, err)
+		}
+		in = append(in, p)
+	}
+
+	out := c.f.Call(in)
+	if err, _ := out[1].Interface().(error); err != nil {
+		return nil, fmt.Errorf("can't build %q: %v", name, err)
+	}
+	t, _ := out[0].Interface().(Transformer)
+	if t == nil {
+		return nil, fmt.Errorf("constructor of %q returned a nil transformer", name)
+	}
+	return Named(name, t), nil
+}
+
+// decodeParams decodes params into a new value of the given type
+func decodeParams(params map[string]interface{}, typ reflect.Type) (reflect.Value, error) {
+	p := reflect.New(typ)
+	if params == nil {
+		return p.Elem(), nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return p.Elem(), nil
+}
+
+// Chain builds transformers with given names without parameters, and chains them
+// see Chain for more details
+func (r *Registry) Chain(names ...string) (Transformer, error) {
+	ts := make([]Transformer, len(names))
+	for i, name := range names {
+		t, err := r.Build(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		ts[i] = t
+	}
+	return Chain(ts...)
+}
@@ -0,0 +1,169 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	fail, err := FromFunction(func(i int) (int, error) { return 0, errors.New("fail") })
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	tr, err := Chain(chainTest(1), Named("fail", fail))
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	err = tr.Transform(context.Background(), 1, make(chan interface{}, 1))
+	var te *TransformError
+	if !errors.As(err, &te) {
+		t.Fatalf("expecting a TransformError, got %v", err)
+	}
+	if want := []string{"chain[1]", "fail"}; !reflect.DeepEqual(te.Path, want) {
+		t.Fatalf("path mismatch\n\thave:\t%v\n\twant:\t%v", te.Path, want)
+	}
+
+	toString, err := FromFunction(func(i int) string { return fmt.Sprint(i) })
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	_, err = Chain(Named("to string", toString), chainTest(1))
+	if err == nil || !strings.Contains(err.Error(), `stage 0 ("to string") outputs string`) {
+		t.Fatalf("expecting an error which contains the name, got %v", err)
+	}
+
+	if node := Describe(tr).Children[1]; node.Name != "fail" || node.Kind != "function" {
+		t.Fatalf("unexpected node: %+v", node)
+	}
+
+	// names don't make transformers streamers, so named functions can have workers
+	if _, ok := Named("fail", fail).(Streamer); ok {
+		t.Fatalf("named function shouldn't be a Streamer")
+	}
+	if _, err := ChainWithOptions(Stage{Transformer: Named("fail", fail), Workers: 4}); err != nil {
+		t.Fatalf("named function should be able to have workers, got %v", err)
+	}
+	if _, ok := Named("batch", batchOf(t, 2)).(Streamer); !ok {
+		t.Fatalf("named Batch should be a Streamer")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	type addParams struct {
+		N int `json:"n"`
+	}
+
+	r := NewRegistry()
+	r.MustRegister("add", func(p addParams) (Transformer, error) {
+		if p.N < 0 {
+			return nil, fmt.Errorf("n can't be negative")
+		}
+		return FromFunction(func(i int) int { return i + p.N })
+	})
+	r.MustRegister("to string", func() (Transformer, error) {
+		return FromFunction(func(i int) string { return fmt.Sprint(i) })
+	})
+
+	// registering
+	for i, c := range []struct {
+		name        string
+		constructor interface{}
+	}{
+		{name: "add", constructor: func() (Transformer, error) { return chainTest(1), nil }}, // duplicate
+		{name: "", constructor: func() (Transformer, error) { return chainTest(1), nil }},
+		{name: "x", constructor: chainTest(1)},
+		{name: "x", constructor: func(int, int) (Transformer, error) { return chainTest(1), nil }},
+		{name: "x", constructor: func() Transformer { return chainTest(1) }},
+		{name: "x", constructor: func() (chainTest, error) { return chainTest(1), nil }},
+	} {
+		t.Run(fmt.Sprintf("register-%d", i+1), func(t *testing.T) {
+			if err := r.Register(c.name, c.constructor); err == nil {
+				t.Fatalf("shouldn't be able to register %T as %q", c.constructor, c.name)
+			}
+		})
+	}
+	if names := r.Names(); !reflect.DeepEqual(names, []string{"add", "to string"}) {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	// building
+	cases := []struct {
+		name   string
+		params map[string]interface{}
+		in     int
+		out    interface{}
+		err    bool
+	}{
+		{name: "add", params: map[string]interface{}{"n": 2}, in: 1, out: 3},
+		{name: "add", in: 1, out: 1},
+		{name: "add", params: map[string]interface{}{"n": -1}, err: true},      // constructor fails
+		{name: "add", params: map[string]interface{}{"m": 1}, err: true},       // unknown param
+		{name: "add", params: map[string]interface{}{"n": "two"}, err: true},   // wrong type
+		{name: "to string", params: map[string]interface{}{"n": 1}, err: true}, // no params
+		{name: "to string", in: 4, out: "4"},
+		{name: "unknown", err: true},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			tr, err := r.Build(c.name, c.params)
+			if err != nil {
+				if !c.err {
+					t.Fatalf("can't build transformer: %v", err)
+				}
+				return
+			}
+			if c.err {
+				t.Fatalf("shouldn't be able to build a transformer")
+			}
+			if Describe(tr).Name != c.name {
+				t.Fatalf("transformer isn't named %q", c.name)
+			}
+			outs, err := collectOutputs(t, tr, c.in)
+			if err != nil {
+				t.Fatalf("can't transform: %v", err)
+			}
+			if len(outs) != 1 || outs[0] != c.out {
+				t.Fatalf("outputs mismatch\n\thave:\t%v\n\twant:\t%v", outs, c.out)
+			}
+		})
+	}
+
+	// chaining
+	tr, err := r.Chain("add", "to string")
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+	if outs, err := collectOutputs(t, tr, 1); err != nil || len(outs) != 1 || outs[0] != "1" {
+		t.Fatalf("unexpected outputs: %v, %v", outs, err)
+	}
+	if _, err := r.Chain("to string", "add"); err == nil {
+		t.Fatalf("shouldn't be able to chain string output into int input")
+	}
+}
+
+func TestRegistryTypes(t *testing.T) {
+	type foo struct{ I int }
+
+	r := NewRegistry()
+	if err := r.RegisterType("foo", reflect.TypeOf(foo{})); err != nil {
+		t.Fatalf("can't register type: %v", err)
+	}
+	if err := r.RegisterType("foo", reflect.TypeOf(1)); err == nil {
+		t.Fatalf("shouldn't be able to register a type with the same name")
+	}
+	if err := r.RegisterType("bar", nil); err == nil {
+		t.Fatalf("shouldn't be able to register a nil type")
+	}
+
+	if typ, ok := r.Type("foo"); !ok || typ != reflect.TypeOf(foo{}) {
+		t.Fatalf("unexpected type: %v", typ)
+	}
+	if _, ok := r.Type("bar"); ok {
+		t.Fatalf("bar shouldn't be registered")
+	}
+}
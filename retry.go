@@ -0,0 +1,152 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"time"
+)
+
+// the wait before the first retry of a policy without MaxAttempts, so it doesn't retry in a busy loop
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// RetryPolicy determines when and how WithRetry retries failed transforms
+// if neither MaxAttempts nor MaxElapsed is set, transform is retried until it succeeds or context is done
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one, 0 means there is no limit
+	MaxAttempts int
+	// MaxElapsed is the time after the first attempt after which transform isn't retried, 0 means there is no limit
+	MaxElapsed time.Duration
+	// InitialBackoff is the wait before the first retry
+	// 0 means retrying immediately if MaxAttempts is set, or 100ms if it isn't
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum wait between two attempts, 0 means there is no limit
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the wait grows after each attempt, values less than 1 mean 2
+	Multiplier float64
+	// Jitter randomizes the wait by up to this fraction of it in both directions, it should be in [0, 1]
+	Jitter float64
+	// Retryable decides if the transform should be retried after the given error
+	// if it's nil, all errors except context.Canceled and context.DeadlineExceeded are retried
+	Retryable func(error) bool
+	// AtLeastOnce makes attempts write their outputs directly to the channel,
+	// which means that outputs written before an attempt failed are written again by the next attempt
+	// if it's not set, outputs of an attempt are buffered and written only if it succeeds
+	AtLeastOnce bool
+}
+
+// backoff returns how long to wait after the given (1-based) attempt failed
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	initial := p.InitialBackoff
+	if initial == 0 && p.MaxAttempts == 0 {
+		initial = defaultRetryBackoff
+	}
+	wait := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+	wait *= 1 + p.Jitter*(2*rand.Float64()-1)
+	return time.Duration(wait)
+}
+
+// retryable checks if transform should be retried after the error
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// RetryError is returned by transformers created with WithRetry when transform fails for the last time
+type RetryError struct {
+	// Attempts is the number of attempts made
+	Attempts int
+	// Err is the error of the last attempt
+	Err error
+}
+
+// Error is part of the error interface
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("failed after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// WithRetry wraps the given transformer into a new one, which retries failed transforms according to the policy
+// while waiting between attempts, ctx.Done() is monitored
+// if transform fails for the last time, a RetryError with the last error is returned
+// if t is a Streamer, transforming fails with ErrStreamer
+func WithRetry(t Transformer, policy RetryPolicy) Transformer {
+	return perValue(t, retryingTransformer{t, policy})
+}
+
+type retryingTransformer struct {
+	Transformer
+	policy RetryPolicy
+}
+
+// OutputType is a part of the OutputTyper interface
+func (t retryingTransformer) OutputType() reflect.Type {
+	return OutputType(t.Transformer)
+}
+
+// Transform is a part of the Transformer interface
+func (t retryingTransformer) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	var c *collector
+	if !t.policy.AtLeastOnce {
+		c = newCollector()
+		defer c.close()
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := t.attempt(ctx, c, v, ch)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || !t.policy.retryable(err) || (t.policy.MaxAttempts > 0 && attempt >= t.policy.MaxAttempts) {
+			return &RetryError{attempt, err}
+		}
+
+		wait := t.policy.backoff(attempt)
+		if t.policy.MaxElapsed > 0 && time.Since(start)+wait > t.policy.MaxElapsed {
+			return &RetryError{attempt, err}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{attempt, ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt transforms the value once, outputs are buffered in the collector if it's not nil
+func (t retryingTransformer) attempt(ctx context.Context, c *collector, v interface{}, ch chan<- interface{}) error {
+	if c == nil {
+		return callTransform(ctx, "", t.Transformer, v, ch)
+	}
+
+	outs, err := c.collect(ctx, "", t.Transformer, v)
+	if err != nil {
+		return err
+	}
+	for _, out := range outs {
+		if err := send(ctx, ch, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// outputs the input, then fails until it's called for the given number of times
+type retryTest struct {
+	calls    *int
+	succeeds int
+}
+
+func (retryTest) InputType() reflect.Type {
+	return reflect.TypeOf(1) // int
+}
+
+func (t retryTest) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	*t.calls++
+	if err := send(ctx, ch, v); err != nil {
+		return err
+	}
+	if *t.calls < t.succeeds {
+		return fmt.Errorf("attempt %d failed", *t.calls)
+	}
+	return send(ctx, ch, v)
+}
+
+func TestWithRetry(t *testing.T) {
+	errPermanent := errors.New("permanent")
+
+	for i, c := range []struct {
+		succeeds int
+		policy   RetryPolicy
+		outs     int
+		attempts int
+		err      bool
+	}{
+		{succeeds: 3, policy: RetryPolicy{MaxAttempts: 3}, outs: 2, attempts: 3},
+		{succeeds: 3, policy: RetryPolicy{MaxAttempts: 3, AtLeastOnce: true}, outs: 4, attempts: 3},
+		{succeeds: 5, policy: RetryPolicy{MaxAttempts: 3}, attempts: 3, err: true},
+		{succeeds: 5, policy: RetryPolicy{MaxElapsed: 25 * time.Millisecond, InitialBackoff: 10 * time.Millisecond}, attempts: 2, err: true},
+		{succeeds: 3, policy: RetryPolicy{Retryable: func(err error) bool { return !errors.Is(err, errPermanent) }}, outs: 2, attempts: 3},
+		{succeeds: 3, policy: RetryPolicy{Retryable: func(error) bool { return false }}, attempts: 1, err: true},
+		{succeeds: 4, policy: RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, Jitter: 0.5}, outs: 2, attempts: 4},
+	} {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			calls := 0
+			tr := WithRetry(retryTest{&calls, c.succeeds}, c.policy)
+
+			outs, err := collectOutputs(t, tr, 1)
+			if calls != c.attempts {
+				t.Fatalf("unexpected number of attempts\n\thave: %d\n\twant: %d", calls, c.attempts)
+			}
+			if err != nil {
+				if !c.err {
+					t.Fatalf("transform shouldn't error out, got %v", err)
+				}
+				var retryErr *RetryError
+				if !errors.As(err, &retryErr) || retryErr.Attempts != c.attempts {
+					t.Fatalf("expecting RetryError with %d attempts, got %v", c.attempts, err)
+				}
+				return
+			}
+			if c.err {
+				t.Fatalf("transform should error out, but didn't")
+			}
+			if len(outs) != c.outs {
+				t.Fatalf("unexpected number of outputs\n\thave: %d\n\twant: %d", len(outs), c.outs)
+			}
+		})
+	}
+}
+
+func TestWithRetryContext(t *testing.T) {
+	calls := 0
+	tr := WithRetry(retryTest{&calls, 100}, RetryPolicy{InitialBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	ch := make(chan interface{}, 10)
+	if err := tr.Transform(ctx, 1, ch); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expecting deadline exceeded, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("unexpected number of attempts\n\thave: %d\n\twant: 1", calls)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	for i, c := range []struct {
+		policy RetryPolicy
+		want   time.Duration
+	}{
+		{policy: RetryPolicy{MaxAttempts: 3}, want: 0},
+		{policy: RetryPolicy{MaxElapsed: time.Second}, want: defaultRetryBackoff},
+		{policy: RetryPolicy{}, want: defaultRetryBackoff},
+		{policy: RetryPolicy{InitialBackoff: time.Millisecond}, want: time.Millisecond},
+	} {
+		if have := c.policy.backoff(1); have != c.want {
+			t.Fatalf("case-%d: backoff mismatch\n\thave: %v\n\twant: %v", i, have, c.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ErrNoRoute is returned by a router without a default branch when a value doesn't match any branch
+var ErrNoRoute = errors.New("no matching route")
+
+// Discard is a Transformer which drops all values
+// use it as the default branch of a router to ignore values which don't match any branch
+var Discard Transformer = discard{}
+
+var emptyInterfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+type discard struct{}
+
+// InputType is part of the Transformer interface
+func (discard) InputType() reflect.Type {
+	return emptyInterfaceType
+}
+
+// Transform is part of the Transformer interface
+func (discard) Transform(context.Context, interface{}, chan<- interface{}) error {
+	return nil
+}
+
+// sends each value to exactly one of the branches
+type router struct {
+	inputType reflect.Type
+	branches  []Transformer
+	// used when pick doesn't find a branch, nil means that's an error
+	fallback Transformer
+	// returns the index of the branch the value should be sent to, or -1 if there is no such branch
+	pick func(interface{}) int
+}
+
+// Case is a branch of a router, taken if Match returns true for a value
+type Case struct {
+	Match func(interface{}) bool
+	Transformer
+}
+
+// Route creates a Transformer which sends each value to the first case which matches it
+// values which don't match any case are sent to fallback
+// if fallback is nil, transforming such a value returns ErrNoRoute, use Discard to drop them
+func Route(fallback Transformer, cases ...Case) (Transformer, error) {
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("need at least one case to route to")
+	}
+	branches := make([]Transformer, len(cases))
+	for i, c := range cases {
+		if c.Match == nil {
+			return nil, fmt.Errorf("case %d (%s) doesn't have a match function", i, nameOf(c.Transformer))
+		}
+		branches[i] = c.Transformer
+	}
+
+	pick := func(v interface{}) int {
+		for i, c := range cases {
+			if c.Match(v) {
+				return i
+			}
+		}
+		return -1
+	}
+	return newRouter(branches, fallback, pick, commonInputType(branches, fallback))
+}
+
+// RouteKey creates a Transformer which sends each value to the branch stored under the value's key
+// values with unknown keys are handled the same way as in Route
+func RouteKey(key func(interface{}) string, branches map[string]Transformer, fallback Transformer) (Transformer, error) {
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("need at least one branch to route to")
+	}
+
+	// sorted, so the order of branches is deterministic
+	keys := make([]string, 0, len(branches))
+	for k := range branches {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ts := make([]Transformer, len(keys))
+	idx := make(map[string]int, len(keys))
+	for i, k := range keys {
+		ts[i] = branches[k]
+		idx[k] = i
+	}
+
+	pick := func(v interface{}) int {
+		if i, ok := idx[key(v)]; ok {
+			return i
+		}
+		return -1
+	}
+	return newRouter(ts, fallback, pick, commonInputType(ts, fallback))
+}
+
+// RouteType creates a Transformer which sends each value to the branch with the same InputType as the value's type
+// if there is no such branch, the first branch with an interface InputType the value implements is used
+// values which don't match any branch are handled the same way as in Route
+// input type of the created transformer is interface{}, so it can transform heterogeneous values
+func RouteType(fallback Transformer, branches ...Transformer) (Transformer, error) {
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("need at least one branch to route to")
+	}
+
+	exact := make(map[reflect.Type]int, len(branches))
+	var interfaces []int
+	for i, t := range branches {
+		typ := t.InputType()
+		if j, exists := exact[typ]; exists {
+			return nil, fmt.Errorf("branches %d (%s) and %d (%s) both expect %s", j, nameOf(branches[j]), i, nameOf(t), typ)
+		}
+		exact[typ] = i
+		if typ.Kind() == reflect.Interface {
+			interfaces = append(interfaces, i)
+		}
+	}
+
+	pick := func(v interface{}) int {
+		typ := reflect.TypeOf(v)
+		if i, ok := exact[typ]; ok {
+			return i
+		}
+		for _, i := range interfaces {
+			if typ != nil && typ.Implements(branches[i].InputType()) {
+				return i
+			}
+		}
+		return -1
+	}
+	return newRouter(branches, fallback, pick, emptyInterfaceType)
+}
+
+func newRouter(branches []Transformer, fallback Transformer, pick func(interface{}) int, inputType reflect.Type) (Transformer, error) {
+	r := &router{
+		inputType: inputType,
+		branches:  branches,
+		fallback:  fallback,
+		pick:      pick,
+	}
+	if _, err := commonOutputType(r.all()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// all returns all branches, including the fallback if it exists
+func (r *router) all() []Transformer {
+	if r.fallback == nil {
+		return r.branches
+	}
+	return append(r.branches[:len(r.branches):len(r.branches)], r.fallback)
+}
+
+// InputType is part of the Transformer interface
+func (r *router) InputType() reflect.Type {
+	return r.inputType
+}
+
+// OutputType is part of the OutputTyper interface
+func (r *router) OutputType() reflect.Type {
+	typ, _ := commonOutputType(r.all())
+	return typ
+}
+
+// children is part of the composite interface, the fallback is the last child if it exists
+func (r *router) children() []Transformer {
+	return r.all()
+}
+
+// stage is part of the composite interface
+func (r *router) stage(i int) string {
+	if i == len(r.branches) {
+		return "route[default]"
+	}
+	return fmt.Sprintf("route[%d]", i)
+}
+
+// withChildren is part of the composite interface
+func (r *router) withChildren(ts []Transformer) Transformer {
+	rewritten := *r
+	rewritten.branches = ts[:len(r.branches)]
+	if r.fallback != nil {
+		rewritten.fallback = ts[len(r.branches)]
+	}
+	return &rewritten
+}
+
+// Transform is part of the Transformer interface
+func (r *router) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	t, stage := r.fallback, r.stage(len(r.branches))
+	if i := r.pick(v); i >= 0 {
+		t, stage = r.branches[i], r.stage(i)
+	}
+	if t == nil {
+		return newTransformError(v, fmt.Errorf("%w for %T", ErrNoRoute, v))
+	}
+	// branches can expect different types, in which case the router accepts anything
+	if in := t.InputType(); in.Kind() != reflect.Interface && (v == nil || !reflect.TypeOf(v).AssignableTo(in)) {
+		return withStage(stage, newTransformError(v, fmt.Errorf("%s expects %s, got %T", nameOf(t), in, v)))
+	}
+	return withStage(stage, newTransformError(v, callTransform(ctx, stage, t, v, ch)))
+}
+
+// commonInputType returns the input type of all branches if it's the same, otherwise interface{}
+// Discard is ignored since it accepts anything
+func commonInputType(branches []Transformer, fallback Transformer) reflect.Type {
+	typ := branches[0].InputType()
+	for _, t := range append(branches[1:len(branches):len(branches)], fallback) {
+		if t == nil || t == Discard {
+			continue
+		}
+		if t.InputType() != typ {
+			return emptyInterfaceType
+		}
+	}
+	return typ
+}
+
+// commonOutputType returns the output type of all transformers, or nil if some of them don't know it
+// Discard is ignored since it never outputs anything
+// returns an error if transformers have different output types
+func commonOutputType(ts []Transformer) (reflect.Type, error) {
+	var typ reflect.Type
+	known := true
+	for i, t := range ts {
+		if t == Discard {
+			continue
+		}
+		tt := OutputType(t)
+		if tt == nil {
+			known = false
+			continue
+		}
+		if typ == nil {
+			typ = tt
+		} else if tt != typ {
+			return nil, fmt.Errorf("branch %d (%s) outputs %s, but other branches output %s", i, nameOf(t), tt, typ)
+		}
+	}
+	if !known {
+		return nil, nil
+	}
+	return typ, nil
+}
@@ -0,0 +1,138 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	neg, _ := FromFunction(func(x int) string { return "negative" })
+	pos, _ := FromFunction(func(x int) string { return "positive" })
+	zero, _ := FromFunction(func(x int) string { return "zero" })
+
+	tr, err := Route(zero,
+		Case{Match: func(v interface{}) bool { return v.(int) < 0 }, Transformer: neg},
+		Case{Match: func(v interface{}) bool { return v.(int) > 0 }, Transformer: pos},
+	)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	if tr.InputType() != reflect.TypeOf(1) || OutputType(tr) != reflect.TypeOf("") {
+		t.Fatalf("unexpected types: %s -> %s", tr.InputType(), OutputType(tr))
+	}
+
+	for in, want := range map[int]string{-5: "negative", 5: "positive", 0: "zero"} {
+		outs, err := collectOutputs(t, tr, in)
+		if err != nil {
+			t.Fatalf("transform shouldn't error out, got %v", err)
+		}
+		if !reflect.DeepEqual(outs, []interface{}{want}) {
+			t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+		}
+	}
+
+	if _, err := Route(nil); err == nil {
+		t.Fatalf("shouldn't be able to create a router without cases")
+	}
+	length, _ := FromFunction(func(x int) int { return x })
+	if _, err := Route(nil, Case{func(interface{}) bool { return true }, pos}, Case{func(interface{}) bool { return true }, length}); err == nil {
+		t.Fatalf("shouldn't be able to create a router with different output types")
+	}
+
+	// branches expect different types, so the router accepts anything and checks the type of the picked branch
+	fromString, _ := FromFunction(func(s string) string { return s })
+	tr, err = Route(nil, Case{func(v interface{}) bool { return v != "foo" }, pos}, Case{func(interface{}) bool { return true }, fromString})
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	if outs, err := collectOutputs(t, tr, "foo"); err != nil || !reflect.DeepEqual(outs, []interface{}{"foo"}) {
+		t.Fatalf("unexpected result: %v, %v", outs, err)
+	}
+	var transformErr *TransformError
+	var panicErr *PanicError
+	_, err = collectOutputs(t, tr, "bar")
+	if !errors.As(err, &transformErr) || !reflect.DeepEqual(transformErr.Path, []string{"route[0]"}) || errors.As(err, &panicErr) {
+		t.Fatalf("expecting a TransformError of route[0], got %v", err)
+	}
+}
+
+func TestRouteKey(t *testing.T) {
+	upper, _ := FromFunction(strings.ToUpper)
+	lower, _ := FromFunction(strings.ToLower)
+	key := func(v interface{}) string { return v.(string)[:1] }
+
+	for i, c := range []struct {
+		fallback Transformer
+		in       string
+		out      []interface{}
+		err      bool
+	}{
+		{in: "uFoo", out: []interface{}{"UFOO"}},
+		{in: "lFoo", out: []interface{}{"lfoo"}},
+		{in: "xFoo", err: true},
+		{fallback: Discard, in: "xFoo"},
+	} {
+		t.Run(fmt.Sprintf("case-%d", i+1), func(t *testing.T) {
+			tr, err := RouteKey(key, map[string]Transformer{"u": upper, "l": lower}, c.fallback)
+			if err != nil {
+				t.Fatalf("can't create transformer: %v", err)
+			}
+			outs, err := collectOutputs(t, tr, c.in)
+			if err != nil {
+				if !c.err {
+					t.Fatalf("transform shouldn't error out, got %v", err)
+				}
+				if !errors.Is(err, ErrNoRoute) {
+					t.Fatalf("expecting ErrNoRoute, got %v", err)
+				}
+				return
+			}
+			if c.err {
+				t.Fatalf("transform should error out, but didn't")
+			}
+			if !reflect.DeepEqual(outs, c.out) {
+				t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, c.out)
+			}
+		})
+	}
+}
+
+func TestRouteType(t *testing.T) {
+	fromInt, _ := FromFunction(func(x int) string { return "int" })
+	fromString, _ := FromFunction(func(s string) string { return "string" })
+	fromError, _ := FromFunction(func(err error) string { return "error" })
+	fromAny, _ := FromFunction(func(v interface{}) string { return "any" })
+
+	tr, err := RouteType(fromAny, fromInt, fromString, fromError)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	if tr.InputType() != emptyInterfaceType {
+		t.Fatalf("input type should be interface{}, got %s", tr.InputType())
+	}
+
+	for _, c := range []struct {
+		in  interface{}
+		out string
+	}{
+		{1, "int"},
+		{"foo", "string"},
+		{fmt.Errorf("foo"), "error"},
+		{1.5, "any"},
+	} {
+		outs, err := collectOutputs(t, tr, c.in)
+		if err != nil {
+			t.Fatalf("transform shouldn't error out, got %v", err)
+		}
+		if !reflect.DeepEqual(outs, []interface{}{c.out}) {
+			t.Fatalf("output mismatch for %T:\n\thave: %v\n\twant: %v", c.in, outs, c.out)
+		}
+	}
+
+	if _, err := RouteType(nil, fromInt, fromInt); err == nil {
+		t.Fatalf("shouldn't be able to route to two branches with the same input type")
+	}
+}
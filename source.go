@@ -0,0 +1,106 @@
+package transform
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Source reads values, e.g. from a file, and sends them to a channel
+type Source interface {
+	// Read sends all values to the channel, without closing it
+	// ctx.Done() should be monitored
+	Read(ctx context.Context, ch chan<- interface{}) error
+}
+
+// Sink writes values it receives from a channel, e.g. to a file
+type Sink interface {
+	// Write writes all values from the channel until it's closed
+	// ctx.Done() should be monitored
+	Write(ctx context.Context, ch <-chan interface{}) error
+}
+
+// LineSource creates a source which sends every line of r as a string, without the line ending
+func LineSource(r io.Reader) Source {
+	return lineSource{r}
+}
+
+type lineSource struct {
+	r io.Reader
+}
+
+// Read is part of the Source interface
+func (s lineSource) Read(ctx context.Context, ch chan<- interface{}) error {
+	scanner := bufio.NewScanner(s.r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		if err := send(ctx, ch, scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// LineSink creates a sink which writes every value, which has to be a string, as a line
+func LineSink(w io.Writer) Sink {
+	return lineSink{w}
+}
+
+type lineSink struct {
+	w io.Writer
+}
+
+// Write is part of the Sink interface
+func (s lineSink) Write(ctx context.Context, ch <-chan interface{}) error {
+	bw := bufio.NewWriter(s.w)
+	for {
+		v, more, err := recv(ctx, ch)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return bw.Flush()
+		}
+		line, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("can't write %T as a line, it isn't a string", v)
+		}
+		bw.WriteString(line)
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+}
+
+// Run reads all values from the source, transforms them with t and writes outputs to the sink
+// it stops at the first error of the source, the transformer or the sink, and returns it
+func Run(ctx context.Context, src Source, t Transformer, sink Sink) error {
+	inCh, outCh := make(chan interface{}), make(chan interface{})
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		defer close(inCh)
+		return src.Read(ctx, inCh)
+	})
+	g.Go(func() error {
+		defer close(outCh)
+		return All(ctx, t, inCh, outCh)
+	})
+	g.Go(func() error {
+		return sink.Write(ctx, outCh)
+	})
+	return g.Wait()
+}
+
+// recv receives a value from the channel, unless the context is done
+// more is false if the channel is closed
+func recv(ctx context.Context, ch <-chan interface{}) (v interface{}, more bool, err error) {
+	select {
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	case v, more = <-ch:
+		return v, more, nil
+	}
+}
@@ -0,0 +1,47 @@
+package transform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+
+	sum, err := FromFunction(func(p point) (int, error) {
+		if p.X < 0 {
+			return 0, errors.New("negative")
+		}
+		return p.X + p.Y, nil
+	})
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+
+	var buf bytes.Buffer
+	src := JSONLinesSource(strings.NewReader(`{"x":1,"y":2}`+"\n"+`{"x":3}`+"\n"), sum.InputType())
+	if err := Run(context.Background(), src, sum, JSONLinesSink(&buf)); err != nil {
+		t.Fatalf("can't run: %v", err)
+	}
+	if buf.String() != "3\n3\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+
+	src = JSONLinesSource(strings.NewReader(`{"x":1}`+"\n"+`{"x":-1}`+"\n"), sum.InputType())
+	err = Run(context.Background(), src, sum, JSONLinesSink(&buf))
+	var te *TransformError
+	if !errors.As(err, &te) || !reflect.DeepEqual(te.Input, point{-1, 0}) {
+		t.Fatalf("expecting a TransformError of the second point, got %v", err)
+	}
+
+	src = JSONLinesSource(strings.NewReader("{\"x\":1}\nnot json\n"), sum.InputType())
+	if err := Run(context.Background(), src, sum, JSONLinesSink(&buf)); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expecting a decoding error at line 2, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package transform
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Stage is a transformer with options on how to run it inside of a chain
+type Stage struct {
+	Transformer
+	// Workers is the number of goroutines transforming values in this stage, 0 means 1
+	// Streamers (e.g. Chain or Batch) can't have multiple workers, since workers transform values one by one
+	Workers int
+	// Buffer is the buffer size of the channel this stage writes its outputs to
+	// it's ignored for the last stage, since it writes to the output channel of the chain
+	Buffer int
+	// Ordered makes a stage with multiple workers emit outputs in the order its inputs were received
+	// all outputs of one input are emitted before any output of the next input
+	// if it's not set, outputs are emitted as soon as they are produced
+	Ordered bool
+}
+
+// all transforms all values from inCh with the stage's workers and sends them to outCh
+// name is the name of the stage in the chain
+func (s Stage) all(ctx context.Context, name string, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	switch {
+	case s.Workers <= 1:
+		return all(ctx, name, s.Transformer, inCh, outCh)
+	case s.Ordered:
+		return allOrdered(ctx, name, s.Transformer, s.Workers, inCh, outCh)
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < s.Workers; i++ {
+		group.Go(func() error { return all(ctx, name, s.Transformer, inCh, outCh) })
+	}
+	return group.Wait()
+}
+
+// allOrdered transforms values with n workers, but emits the outputs in the order inputs were received
+// outputs of each input are buffered until all outputs of previous inputs are emitted
+// stage is the name of the transformer, see all
+func allOrdered(ctx context.Context, stage string, t Transformer, n int, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	type job struct {
+		v      interface{}
+		result chan []interface{}
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	jobs := make(chan job)
+	// results in the order of inputs, this also bounds the number of buffered results
+	pending := make(chan chan []interface{}, 2*n)
+
+	group.Go(func() error {
+		defer close(pending)
+		defer close(jobs)
+		for {
+			select {
+			case v, more := <-inCh:
+				if !more {
+					return nil
+				}
+				j := job{v, make(chan []interface{}, 1)}
+				select {
+				case pending <- j.result:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				select {
+				case jobs <- j:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+
+	for i := 0; i < n; i++ {
+		group.Go(func() error {
+			c := newCollector()
+			defer c.close()
+			for j := range jobs {
+				outs, err := c.collect(ctx, stage, t, j.v)
+				if err != nil {
+					return newTransformError(j.v, err)
+				}
+				j.result <- outs
+			}
+			return nil
+		})
+	}
+
+	group.Go(func() error {
+		for result := range pending {
+			var outs []interface{}
+			select {
+			case outs = <-result:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			for _, out := range outs {
+				select {
+				case outCh <- out:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		return nil
+	})
+
+	return group.Wait()
+}
+
+// flush is sent to the collector after Transform returns, it's unexported so no transformer can output it
+type flush struct{}
+
+// collector gathers all outputs of Transform calls
+// a single goroutine is used for all calls, instead of spawning a new one for each of them
+type collector struct {
+	ch   chan interface{}
+	outs chan []interface{}
+}
+
+func newCollector() *collector {
+	c := &collector{
+		ch:   make(chan interface{}),
+		outs: make(chan []interface{}),
+	}
+	go func() {
+		defer close(c.outs)
+		var outs []interface{}
+		for out := range c.ch {
+			if _, ok := out.(flush); ok {
+				c.outs <- outs
+				outs = nil
+				continue
+			}
+			outs = append(outs, out)
+		}
+	}()
+	return c
+}
+
+// collect calls t.Transform and returns all outputs it produced, even if it failed
+// stage is the name of the transformer, see callTransform
+func (c *collector) collect(ctx context.Context, stage string, t Transformer, v interface{}) ([]interface{}, error) {
+	err := callTransform(ctx, stage, t, v, c.ch)
+	c.ch <- flush{}
+	return <-c.outs, err
+}
+
+// close stops the collector goroutine, collector can't be used after this
+func (c *collector) close() {
+	close(c.ch)
+}
@@ -0,0 +1,137 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sleeps for a random duration, then outputs the input twice
+type stageTest struct{}
+
+func (stageTest) InputType() reflect.Type {
+	return reflect.TypeOf(1) // int
+}
+
+func (stageTest) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ch <- v:
+		}
+	}
+	return nil
+}
+
+func runStages(t *testing.T, numInputs int, stages ...Stage) []int {
+	t.Helper()
+	tr, err := ChainWithOptions(stages...)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	inCh := make(chan interface{})
+	go func() {
+		defer close(inCh)
+		for i := 0; i < numInputs; i++ {
+			inCh <- i
+		}
+	}()
+
+	outCh := make(chan interface{})
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(outCh)
+		errCh <- All(context.Background(), tr, inCh, outCh)
+	}()
+
+	var outs []int
+	for out := range outCh {
+		outs = append(outs, out.(int))
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("transform shouldn't error out, got %v", err)
+	}
+	return outs
+}
+
+func TestChainWithOptions(t *testing.T) {
+	const numInputs = 500
+
+	t.Run("ordered", func(t *testing.T) {
+		outs := runStages(t, numInputs,
+			Stage{Transformer: stageTest{}, Workers: 8, Buffer: 4, Ordered: true},
+			Stage{Transformer: stageTest{}, Workers: 4, Ordered: true},
+		)
+		if len(outs) != 4*numInputs {
+			t.Fatalf("unexpected number of outputs\n\thave: %d\n\twant: %d", len(outs), 4*numInputs)
+		}
+		for i, out := range outs {
+			if out != i/4 {
+				t.Fatalf("output %d is out of order: got %d, expecting %d", i, out, i/4)
+			}
+		}
+	})
+
+	t.Run("unordered", func(t *testing.T) {
+		outs := runStages(t, numInputs,
+			Stage{Transformer: stageTest{}, Workers: 8, Buffer: 4},
+			Stage{Transformer: stageTest{}, Workers: 4},
+		)
+		counts := map[int]int{}
+		for _, out := range outs {
+			counts[out]++
+		}
+		for i := 0; i < numInputs; i++ {
+			if counts[i] != 4 {
+				t.Fatalf("input %d produced %d outputs, expecting 4", i, counts[i])
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for i, stages := range [][]Stage{
+			nil,
+			{{Transformer: stageTest{}, Workers: -1}, {Transformer: stageTest{}}},
+			{{Transformer: stageTest{}, Buffer: -1}, {Transformer: stageTest{}}},
+			{{Transformer: batchOf(t, 2), Workers: 2}}, // streamers can't have workers
+		} {
+			if _, err := ChainWithOptions(stages...); err == nil {
+				t.Fatalf("case-%d: shouldn't be able to chain transformers", i+1)
+			}
+		}
+	})
+}
+
+func TestOrderedError(t *testing.T) {
+	fail, _ := FromFunction(func(x int) (int, error) {
+		if x == 42 {
+			return 0, fmt.Errorf("failed on %d", x)
+		}
+		return x, nil
+	})
+
+	inCh := make(chan interface{})
+	go func() {
+		defer close(inCh)
+		for i := 0; i < 100; i++ {
+			inCh <- i
+		}
+	}()
+
+	outCh := make(chan interface{})
+	go func() {
+		for range outCh {
+		}
+	}()
+	defer close(outCh)
+
+	if err := AllParallel(context.Background(), fail, 4, inCh, outCh); err == nil {
+		t.Fatalf("transform should error out, but didn't")
+	}
+}
@@ -24,6 +24,11 @@ func (t *structTransformer) InputType() reflect.Type {
 	return t.inputType
 }
 
+// OutputType is part of the OutputTyper interface
+func (t *structTransformer) OutputType() reflect.Type {
+	return t.outputType
+}
+
 // Transform is part of the Transformer interface
 func (t *structTransformer) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
 	inValue := reflect.ValueOf(v)
@@ -123,12 +128,19 @@ func buildSubtypeAndIdx(typ reflect.Type, names []string) (*reflect.Type, []int,
 
 // GetStructFieldName returns the name to be used in transformations
 // if a field is tagged with 'hive', then that name is used
+// the tag can have options after the name, separated by commas, e.g. `hive:"price,decimal(10,2)"`, see HiveDDL
+// if the name in the tag is empty, e.g. `hive:",partition"`, the name of the field is used
 // name is always lowercase
 func GetStructFieldName(sf reflect.StructField) string {
 	name := sf.Name
 	// check if it has a tag
 	if tag, ok := sf.Tag.Lookup("hive"); ok {
-		name = tag
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			tag = tag[:i]
+		}
+		if tag != "" {
+			name = tag
+		}
 	}
 	return strings.ToLower(name)
 }
@@ -214,13 +214,15 @@ func TestGetStructFieldName(t *testing.T) {
 	type foo struct {
 		Name  int
 		Name2 int `hive:"name"`
+		Price int `hive:"Price,decimal(10,2)"`
+		Day   int `hive:",partition"`
+		Empty int `hive:""`
 	}
 
 	typ := reflect.TypeOf(foo{})
-	if name := GetStructFieldName(typ.Field(0)); name != "name" {
-		t.Fatalf("expecting %q, got %q", "name", name)
-	}
-	if name := GetStructFieldName(typ.Field(1)); name != "name" {
-		t.Fatalf("expecting %q, got %q", "name", name)
+	for i, expect := range []string{"name", "name", "price", "day", "empty"} {
+		if name := GetStructFieldName(typ.Field(i)); name != expect {
+			t.Fatalf("expecting %q, got %q", expect, name)
+		}
 	}
 }
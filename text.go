@@ -0,0 +1,132 @@
+package transform
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	stringType          = reflect.TypeOf("")
+	bytesType           = reflect.TypeOf([]byte(nil))
+)
+
+// timeLayouts are the layouts parseText tries to parse times with, in order
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+// isText checks if values of the type can be parsed with parseText
+func isText(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == timeType || reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// parseText sets v to the value parsed from its text representation s, used by text formats like CSV
+// pointers are nullable, so they're set to nil if s is empty
+// times are parsed with timeLayouts, other types which implement encoding.TextUnmarshaler use it
+func parseText(s string, v reflect.Value) error {
+	typ := v.Type()
+	switch {
+	case typ.Kind() == reflect.Ptr:
+		if s == "" {
+			v.Set(reflect.Zero(typ))
+			return nil
+		}
+		elem := reflect.New(typ.Elem())
+		if err := parseText(s, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case typ == timeType:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("can't parse time %q", s)
+	case reflect.PtrTo(typ).Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, typ.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("can't parse %s from text", typ)
+	}
+	return nil
+}
+
+// formatText returns the text representation of v, which parseText parses back into the same value
+// nil pointers are formatted as empty strings
+func formatText(v reflect.Value) (string, error) {
+	typ := v.Type()
+	switch {
+	case typ.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			return "", nil
+		}
+		return formatText(v.Elem())
+	case typ == timeType:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	case typ.Implements(textMarshalerType):
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, typ.Bits()), nil
+	}
+	return "", fmt.Errorf("can't format %s as text", typ)
+}
@@ -0,0 +1,108 @@
+package transform
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracer starts spans around Transform calls of transformers which composite transformers are built from
+// (e.g. stages of a Chain, transformers of InParallel, branches of routers)
+// spans of one stage's Transform call are children of the span in the context passed to it,
+// so transformers nested in InParallel or a router are children of it's span.
+// stages of a Chain transform a stream of values, so their spans are children of the span
+// in the context the chain is run with (all spans of a stage inside of a nested chain are the children of it)
+type Tracer interface {
+	// StartSpan starts a span of transforming the input in the given stage
+	// stage is the path of the stage, the same as in TransformError, joined with "/"
+	// returned context is passed to Transform, and end is called with the error Transform returns
+	StartSpan(ctx context.Context, stage string, input interface{}) (context.Context, func(error))
+}
+
+type tracerKey struct{}
+
+// stagePathKey is the key of the path of the stage which is being transformed
+type stagePathKey struct{}
+
+// WithTracer returns a context with the tracer, transformers run with it will be traced
+func WithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// stageContext returns a context for running the stage, stages nested in it will have its path as a prefix
+// paths are only tracked when tracing, since they're not needed otherwise
+func stageContext(ctx context.Context, stage string) context.Context {
+	if stage == "" || ctx.Value(tracerKey{}) == nil {
+		return ctx
+	}
+	if parent, ok := ctx.Value(stagePathKey{}).(string); ok {
+		stage = parent + "/" + stage
+	}
+	return context.WithValue(ctx, stagePathKey{}, stage)
+}
+
+// startSpan starts a span of transforming v in the stage, if there is a tracer in the context
+func startSpan(ctx context.Context, stage string, v interface{}) (context.Context, func(error)) {
+	if stage == "" {
+		return ctx, func(error) {}
+	}
+	tracer, ok := ctx.Value(tracerKey{}).(Tracer)
+	if !ok {
+		return ctx, func(error) {}
+	}
+	ctx = stageContext(ctx, stage)
+	return tracer.StartSpan(ctx, ctx.Value(stagePathKey{}).(string), v)
+}
+
+// RecordedSpan is a span recorded by a SpanRecorder
+type RecordedSpan struct {
+	// ID of the span, starting from 1
+	ID int
+	// ParentID is the ID of the parent span, 0 if it doesn't have one
+	ParentID int
+	Stage    string
+	Input    interface{}
+	Err      error
+	Start    time.Time
+	End      time.Time
+}
+
+// SpanRecorder is a Tracer which keeps all spans in memory, it's meant to be used in tests
+// zero value is ready to use
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+type spanIDKey struct{}
+
+// StartSpan is part of the Tracer interface
+func (r *SpanRecorder) StartSpan(ctx context.Context, stage string, input interface{}) (context.Context, func(error)) {
+	parentID, _ := ctx.Value(spanIDKey{}).(int)
+
+	r.mu.Lock()
+	id := len(r.spans) + 1
+	r.spans = append(r.spans, RecordedSpan{ID: id, ParentID: parentID, Stage: stage, Input: input, Start: time.Now()})
+	r.mu.Unlock()
+
+	end := func(err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.spans[id-1].Err = err
+		r.spans[id-1].End = time.Now()
+	}
+	return context.WithValue(ctx, spanIDKey{}, id), end
+}
+
+// Spans returns all spans which ended, ordered by their ID
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var spans []RecordedSpan
+	for _, span := range r.spans {
+		if !span.End.IsZero() {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
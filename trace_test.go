@@ -0,0 +1,83 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	inc, _ := FromFunction(func(x int) int { return x + 1 })
+	double, _ := FromFunction(func(x int) int { return 2 * x })
+	check, _ := FromFunction(func(x int) (int, error) {
+		if x > 2 {
+			return 0, errors.New("too big")
+		}
+		return x, nil
+	})
+
+	par, err := InParallel(double, check)
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+	inner, err := Chain(inc, par)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+	tr, err := Chain(inc, inner)
+	if err != nil {
+		t.Fatalf("can't chain transformers: %v", err)
+	}
+
+	var recorder SpanRecorder
+	ctx := WithTracer(context.Background(), &recorder)
+
+	inCh := make(chan interface{}, 2)
+	inCh <- 0
+	inCh <- 1
+	close(inCh)
+	outCh := make(chan interface{}, 10)
+	if err := All(ctx, tr, inCh, outCh); err == nil {
+		t.Fatalf("transform should error out, but didn't")
+	}
+
+	spans := recorder.Spans()
+	byID := map[int]RecordedSpan{}
+	for _, span := range spans {
+		byID[span.ID] = span
+	}
+
+	var have []string
+	for _, span := range spans {
+		parent := "-"
+		if span.ParentID != 0 {
+			parent = byID[span.ParentID].Stage
+		}
+		errMsg := ""
+		// parallel[0] can be cancelled by parallel[1] failing, or finish before that
+		if span.Err != nil && !errors.Is(span.Err, context.Canceled) {
+			errMsg = " failed"
+		}
+		have = append(have, fmt.Sprintf("%s(%v) <- %s%s", span.Stage, span.Input, parent, errMsg))
+	}
+	sort.Strings(have)
+
+	want := []string{
+		"chain[0](0) <- -",
+		"chain[0](1) <- -",
+		"chain[1]/chain[0](1) <- -",
+		"chain[1]/chain[0](2) <- -",
+		"chain[1]/chain[1](2) <- -",
+		"chain[1]/chain[1](3) <- - failed",
+		"chain[1]/chain[1]/parallel[0](2) <- chain[1]/chain[1]",
+		"chain[1]/chain[1]/parallel[0](3) <- chain[1]/chain[1]",
+		"chain[1]/chain[1]/parallel[1](2) <- chain[1]/chain[1]",
+		"chain[1]/chain[1]/parallel[1](3) <- chain[1]/chain[1] failed",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("spans mismatch:\n\thave: %v\n\twant: %v", have, want)
+	}
+}
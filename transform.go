@@ -2,7 +2,7 @@ package transform
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"reflect"
 )
 
@@ -17,22 +17,91 @@ type Transformer interface {
 	Transform(context.Context, interface{}, chan<- interface{}) error
 }
 
+// Streamer is an optional interface for transformers which can transform a whole stream of values
+// more efficiently than by calling Transform for every value
+type Streamer interface {
+	// TransformAll transforms all values from the input channel and sends them to the output channel
+	// it has the same semantics as All, which will use it instead of calling Transform for every value
+	TransformAll(ctx context.Context, inCh <-chan interface{}, outCh chan<- interface{}) error
+}
+
+// ErrStreamer is the error of wrappers which transform values one by one (WithRetry, WithTimeout, error handlers, ...)
+// when they wrap a Streamer, since values of a stream can't be e.g. retried one by one
+// wrap the transformers the Streamer is built from instead, e.g. with Apply
+var ErrStreamer = errors.New("can't transform values of a Streamer one by one")
+
+// transformOne transforms a single value with the streamer, by streaming a channel with only that value
+func transformOne(ctx context.Context, s Streamer, v interface{}, ch chan<- interface{}) error {
+	inCh := make(chan interface{}, 1)
+	inCh <- v
+	close(inCh)
+	return s.TransformAll(ctx, inCh, ch)
+}
+
+// OutputTyper is an optional interface for transformers which know the type of values they output
+type OutputTyper interface {
+	// OutputType returns the type of all values written to the output channel
+	// nil means that the output type isn't known
+	OutputType() reflect.Type
+}
+
+// OutputType returns the output type of the given transformer
+// returns nil if the transformer doesn't implement OutputTyper or doesn't know its output type
+func OutputType(t Transformer) reflect.Type {
+	if ot, ok := t.(OutputTyper); ok {
+		return ot.OutputType()
+	}
+	return nil
+}
+
+// assignable checks if values of type out can be passed to a transformer with input type in
+// unknown types and interface outputs can only be checked at runtime, so they are always assignable
+func assignable(out, in reflect.Type) bool {
+	if out == nil || in == nil {
+		return true
+	}
+	return out.Kind() == reflect.Interface || out.AssignableTo(in)
+}
+
 // All will transform all values in the input channel and send them to the output channel
 // input channel needs to be created and closed outside of this function
 // Since this function is blocking, output channel can be closed when this function finishes
-// Returns an error if transforming fails or context is done
+// Returns a TransformError if transforming fails, or the context error if context is done
+// If the transformer is a Streamer, the whole input channel is handed over to it
 func All(ctx context.Context, t Transformer, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	return all(ctx, "", t, inCh, outCh)
+}
+
+// all is the same as All, but every Transform call is traced as the given stage
+// streaming transformers aren't traced, since they don't transform values one by one,
+// but transformers nested in them will be traced as if they were nested in the stage
+func all(ctx context.Context, stage string, t Transformer, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	if s, ok := t.(Streamer); ok {
+		return safeTransformAll(stageContext(ctx, stage), s, inCh, outCh, RecoverPanics)
+	}
 	for {
 		select {
 		case v, more := <-inCh:
 			if !more {
 				return nil
 			}
-			if err := t.Transform(ctx, v, outCh); err != nil {
-				return fmt.Errorf("transform(%+v) error: %v", v, err)
+			if err := callTransform(ctx, stage, t, v, outCh); err != nil {
+				return newTransformError(v, err)
 			}
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
 }
+
+// AllParallel is the same as All, but values are transformed by n workers in parallel
+// outputs are sent to the output channel in the same order as the inputs were received,
+// and all outputs of one input are sent before any output of the next one
+// Returns an error if transforming fails or context is done
+// a Streamer is transformed with All, since transforming its values one by one would change what it does
+func AllParallel(ctx context.Context, t Transformer, n int, inCh <-chan interface{}, outCh chan<- interface{}) error {
+	if _, ok := t.(Streamer); ok || n <= 1 {
+		return All(ctx, t, inCh, outCh)
+	}
+	return allOrdered(ctx, "", t, n, inCh, outCh)
+}
@@ -0,0 +1,60 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// collectOutputs transforms a single value and returns all outputs
+func collectOutputs(t *testing.T, tr Transformer, v interface{}) ([]interface{}, error) {
+	t.Helper()
+	ch := make(chan interface{})
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		errCh <- tr.Transform(context.Background(), v, ch)
+	}()
+
+	var outs []interface{}
+	for out := range ch {
+		outs = append(outs, out)
+	}
+	return outs, <-errCh
+}
+
+func TestAllParallel(t *testing.T) {
+	for _, n := range []int{0, 1, 4, 16} {
+		t.Run(fmt.Sprintf("workers-%d", n), func(t *testing.T) {
+			const numInputs = 200
+			inCh := make(chan interface{})
+			go func() {
+				defer close(inCh)
+				for i := 0; i < numInputs; i++ {
+					inCh <- i
+				}
+			}()
+
+			outCh := make(chan interface{})
+			errCh := make(chan error, 1)
+			go func() {
+				defer close(outCh)
+				errCh <- AllParallel(context.Background(), stageTest{}, n, inCh, outCh)
+			}()
+
+			i := 0
+			for out := range outCh {
+				if out != i/2 {
+					t.Fatalf("output %d is out of order: got %d, expecting %d", i, out, i/2)
+				}
+				i++
+			}
+			if err := <-errCh; err != nil {
+				t.Fatalf("transform shouldn't error out, got %v", err)
+			}
+			if i != 2*numInputs {
+				t.Fatalf("unexpected number of outputs\n\thave: %d\n\twant: %d", i, 2*numInputs)
+			}
+		})
+	}
+}
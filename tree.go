@@ -0,0 +1,28 @@
+package transform
+
+// composite is implemented by transformers which are built from other transformers
+// it's used to walk through the tree of transformers
+type composite interface {
+	Transformer
+	// children returns the transformers this one is built from
+	children() []Transformer
+	// stage returns the name of the i-th child in stage paths, e.g. "chain[1]"
+	stage(i int) string
+	// withChildren returns a copy of this transformer, built from the given children instead
+	withChildren([]Transformer) Transformer
+}
+
+// rewrite walks the tree of transformers bottom up, and replaces every transformer t with f(path, t)
+// composites are rebuilt from rewritten children before they're passed to f
+// path of a transformer is the same as the Path of TransformErrors it returns
+func rewrite(t Transformer, path []string, f func([]string, Transformer) Transformer) Transformer {
+	if c, ok := t.(composite); ok {
+		children := c.children()
+		rewritten := make([]Transformer, len(children))
+		for i, child := range children {
+			rewritten[i] = rewrite(child, append(path[:len(path):len(path)], c.stage(i)), f)
+		}
+		t = c.withChildren(rewritten)
+	}
+	return f(path, t)
+}
@@ -0,0 +1,212 @@
+// Package typed is a generic, type-safe counterpart of the transform package
+// values are passed over typed channels, so type mismatches are caught at compile time
+// use ToTransformer and FromTransformer to mix typed and untyped transformers in one pipeline
+package typed
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+
+	"github.com/n1chre/transform"
+	"golang.org/x/sync/errgroup"
+)
+
+// Transformer is an interface which knows how to transform a value of type In into values of type Out
+type Transformer[In, Out any] interface {
+	// Transform is the function which transforms a value to another (or multiple)
+	// write all outputs to the channel, without closing it
+	// ctx.Done() should be monitored
+	Transform(context.Context, In, chan<- Out) error
+}
+
+// Func is a function which implements the Transformer interface
+// every input is transformed into exactly one output
+type Func[In, Out any] func(context.Context, In) (Out, error)
+
+// Transform is part of the Transformer interface
+func (f Func[In, Out]) Transform(ctx context.Context, v In, ch chan<- Out) error {
+	out, err := f(ctx, v)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case ch <- out:
+		return nil
+	}
+}
+
+// FromFunc constructs a Transformer from the given function
+// unlike transform.FromFunction, the function is called directly instead of through reflection
+func FromFunc[In, Out any](f func(context.Context, In) (Out, error)) Transformer[In, Out] {
+	return Func[In, Out](f)
+}
+
+type chain2[A, B, C any] struct {
+	first  Transformer[A, B]
+	second Transformer[B, C]
+}
+
+// Chain2 creates a Transformer which runs t2 on every output of t1
+func Chain2[A, B, C any](t1 Transformer[A, B], t2 Transformer[B, C]) Transformer[A, C] {
+	return chain2[A, B, C]{t1, t2}
+}
+
+// Chain3 creates a Transformer which runs t3 on every output of t2, and t2 on every output of t1
+func Chain3[A, B, C, D any](t1 Transformer[A, B], t2 Transformer[B, C], t3 Transformer[C, D]) Transformer[A, D] {
+	return Chain2(Chain2(t1, t2), t3)
+}
+
+// Transform is part of the Transformer interface
+func (c chain2[A, B, C]) Transform(ctx context.Context, v A, ch chan<- C) error {
+	group, ctx := errgroup.WithContext(ctx)
+	mid := make(chan B)
+	group.Go(func() error {
+		defer close(mid)
+		return callTransform(ctx, c.first, v, mid)
+	})
+	group.Go(func() error {
+		return All(ctx, c.second, mid, ch)
+	})
+	return group.Wait()
+}
+
+// All will transform all values in the input channel and send them to the output channel
+// it behaves the same as transform.All, and also returns a transform.TransformError if transforming fails
+func All[In, Out any](ctx context.Context, t Transformer[In, Out], inCh <-chan In, outCh chan<- Out) error {
+	for {
+		select {
+		case v, more := <-inCh:
+			if !more {
+				return nil
+			}
+			if err := callTransform(ctx, t, v, outCh); err != nil {
+				if _, ok := err.(*transform.TransformError); ok {
+					return err
+				}
+				return &transform.TransformError{Input: transform.RedactInput(v), Err: err}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// callTransform calls t.Transform, and returns a panic in it as a transform.TransformError
+// if transform.RecoverPanics is enabled, the same way combinators in package transform do
+func callTransform[In, Out any](ctx context.Context, t Transformer[In, Out], v In, ch chan<- Out) (err error) {
+	if transform.RecoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &transform.TransformError{
+					Input: transform.RedactInput(v),
+					Err:   &transform.PanicError{Value: r, Stack: debug.Stack()},
+				}
+			}
+		}()
+	}
+	return t.Transform(ctx, v, ch)
+}
+
+// typeOf returns the reflect.Type of T, also when T is an interface
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+type untyped[In, Out any] struct {
+	t Transformer[In, Out]
+}
+
+// ToTransformer converts a typed Transformer into a transform.Transformer
+// input type is In and output type is Out
+func ToTransformer[In, Out any](t Transformer[In, Out]) transform.Transformer {
+	return untyped[In, Out]{t}
+}
+
+// InputType is part of the transform.Transformer interface
+func (u untyped[In, Out]) InputType() reflect.Type {
+	return typeOf[In]()
+}
+
+// OutputType is part of the transform.OutputTyper interface
+func (u untyped[In, Out]) OutputType() reflect.Type {
+	return typeOf[Out]()
+}
+
+// Transform is part of the transform.Transformer interface
+func (u untyped[In, Out]) Transform(ctx context.Context, v interface{}, ch chan<- interface{}) error {
+	in, ok := v.(In)
+	if !ok {
+		return fmt.Errorf("input should be %s, got %T", typeOf[In](), v)
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	outCh := make(chan Out)
+	group.Go(func() error {
+		defer close(outCh)
+		return callTransform(ctx, u.t, in, outCh)
+	})
+	group.Go(func() error {
+		for out := range outCh {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ch <- out:
+			}
+		}
+		return nil
+	})
+	return group.Wait()
+}
+
+type typed[In, Out any] struct {
+	t transform.Transformer
+}
+
+// FromTransformer converts a transform.Transformer into a typed Transformer
+// returns an error if In can't be passed to the transformer,
+// or if the transformer knows its output type and it can't be converted to Out
+// outputs of transformers which don't know their output type are checked at runtime
+func FromTransformer[In, Out any](t transform.Transformer) (Transformer[In, Out], error) {
+	if in := typeOf[In](); !in.AssignableTo(t.InputType()) {
+		return nil, fmt.Errorf("transformer expects %s, can't use it with %s", t.InputType(), in)
+	}
+	if out := transform.OutputType(t); out != nil && out.Kind() != reflect.Interface && !out.AssignableTo(typeOf[Out]()) {
+		return nil, fmt.Errorf("transformer outputs %s, can't use it as %s", out, typeOf[Out]())
+	}
+	return typed[In, Out]{t}, nil
+}
+
+// Transform is part of the Transformer interface
+func (t typed[In, Out]) Transform(ctx context.Context, v In, ch chan<- Out) error {
+	tr := t.t
+	if transform.RecoverPanics {
+		tr = transform.Recover(tr)
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	outCh := make(chan interface{})
+	group.Go(func() error {
+		defer close(outCh)
+		return tr.Transform(ctx, v, outCh)
+	})
+	group.Go(func() error {
+		for iface := range outCh {
+			out, ok := iface.(Out)
+			if !ok {
+				return fmt.Errorf("output should be %s, got %T", typeOf[Out](), iface)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ch <- out:
+			}
+		}
+		return nil
+	})
+	return group.Wait()
+}
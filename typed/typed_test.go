@@ -0,0 +1,99 @@
+package typed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/n1chre/transform"
+)
+
+func collect[In, Out any](t *testing.T, tr Transformer[In, Out], in In) ([]Out, error) {
+	t.Helper()
+	ch := make(chan Out)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		errCh <- tr.Transform(context.Background(), in, ch)
+	}()
+
+	var outs []Out
+	for out := range ch {
+		outs = append(outs, out)
+	}
+	return outs, <-errCh
+}
+
+func TestChain(t *testing.T) {
+	square := FromFunc(func(_ context.Context, x int) (int, error) { return x * x, nil })
+	itoa := FromFunc(func(_ context.Context, x int) (string, error) { return strconv.Itoa(x), nil })
+	quote := FromFunc(func(_ context.Context, s string) (string, error) { return strconv.Quote(s), nil })
+
+	outs, err := collect(t, Chain3(square, itoa, quote), 5)
+	if err != nil {
+		t.Fatalf("transform shouldn't error out, got %v", err)
+	}
+	if want := []string{`"25"`}; !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+
+	fail := FromFunc(func(_ context.Context, x int) (int, error) { return 0, fmt.Errorf("failed on %d", x) })
+	if _, err := collect(t, Chain2(fail, itoa), 1); err == nil {
+		t.Fatalf("transform should error out, but didn't")
+	}
+}
+
+func TestAdapters(t *testing.T) {
+	square, err := transform.FromFunction(func(x int) int { return x * x })
+	if err != nil {
+		t.Fatalf("can't create transformer: %v", err)
+	}
+
+	if _, err := FromTransformer[string, int](square); err == nil {
+		t.Fatalf("shouldn't be able to use int transformer with string input")
+	}
+	if _, err := FromTransformer[int, string](square); err == nil {
+		t.Fatalf("shouldn't be able to use int transformer with string output")
+	}
+
+	typedSquare, err := FromTransformer[int, int](square)
+	if err != nil {
+		t.Fatalf("can't convert transformer: %v", err)
+	}
+	itoa := FromFunc(func(_ context.Context, x int) (string, error) { return strconv.Itoa(x), nil })
+
+	// typed -> untyped -> typed
+	tr, err := FromTransformer[int, string](ToTransformer(Chain2(typedSquare, itoa)))
+	if err != nil {
+		t.Fatalf("can't convert transformer: %v", err)
+	}
+
+	outs, err := collect(t, tr, 4)
+	if err != nil {
+		t.Fatalf("transform shouldn't error out, got %v", err)
+	}
+	if want := []string{"16"}; !reflect.DeepEqual(outs, want) {
+		t.Fatalf("output mismatch:\n\thave: %v\n\twant: %v", outs, want)
+	}
+
+	untyped := ToTransformer(itoa)
+	if untyped.InputType() != reflect.TypeOf(1) || transform.OutputType(untyped) != reflect.TypeOf("") {
+		t.Fatalf("unexpected types: %s -> %s", untyped.InputType(), transform.OutputType(untyped))
+	}
+	if err := untyped.Transform(context.Background(), "not an int", nil); err == nil {
+		t.Fatalf("transform should error out on wrong input type, but didn't")
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	div := FromFunc(func(_ context.Context, x int) (int, error) { return 100 / x, nil })
+
+	err := ToTransformer(div).Transform(context.Background(), 0, make(chan interface{}))
+	var panicErr *transform.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expecting a panic error, got %v", err)
+	}
+}